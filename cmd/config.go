@@ -1,8 +1,85 @@
 package main
 
+import (
+	"github.com/iamjinlei/proteus/gen"
+)
+
 type Config struct {
-	Domain        string            `yaml:"domain"`
-	EnableSitemap bool              `yaml:"enable_sitemap"`
-	Entry         string            `yaml:"entry"`
-	Assets        map[string]string `yaml:"assets"`
+	Domain        string             `yaml:"domain"`
+	EnableSitemap bool               `yaml:"enable_sitemap"`
+	Entry         string             `yaml:"entry"`
+	Assets        map[string]string  `yaml:"assets"`
+	PrevNext      bool               `yaml:"prev_next"`
+	Order         []string           `yaml:"order"`
+	ToC           ToCConfig          `yaml:"toc"`
+	LinkProviders map[string]string  `yaml:"link_providers"`
+	KeywordTypes  map[string]string  `yaml:"keyword_types"`
+	Glossary      string             `yaml:"glossary"`
+	Math          string             `yaml:"math"`
+	Diagrams      map[string]string  `yaml:"diagrams"`
+	DiagramCache  string             `yaml:"diagram_cache"`
+	Extensions    []string           `yaml:"markdown_extensions"`
+	Images        *ImageConfig       `yaml:"images"`
+	Lightbox      bool               `yaml:"lightbox"`
+	Fingerprint   bool               `yaml:"fingerprint"`
+	SharedStatic  bool               `yaml:"shared_static"`
+	StaticPath    string             `yaml:"static_path"`
+	Minify        bool               `yaml:"minify"`
+	DarkMode      bool               `yaml:"dark_mode"`
+	ThemeToggle   bool               `yaml:"theme_toggle"`
+	Palette       *gen.PaletteConfig `yaml:"palette"`
+	DarkPalette   *gen.PaletteConfig `yaml:"dark_palette"`
+	// PaletteFile is a YAML file with palette and dark_palette configs,
+	// which are overridden by the ones in the config file.
+	PaletteFile string `yaml:"palette_file"`
+	// Theme is the name of a theme in the themes dir of the site. Files in
+	// the theme dir of the site override the ones of the theme.
+	Theme string `yaml:"theme"`
+	// Blog enables listing pages of the dated pages in the posts dir.
+	Blog *BlogConfig `yaml:"blog"`
+	// Feed enables the RSS and Atom feeds of dated pages, for the site and
+	// per tag.
+	Feed *FeedConfig `yaml:"feed"`
+	// PlantUMLServer is the PlantUML server rendering plantuml diagrams
+	// without a command, e.g., "https://www.plantuml.com/plantuml/svg/".
+	// Diagram sources are sent to it, so there is none by default.
+	PlantUMLServer string `yaml:"plantuml_server"`
+}
+
+type ToCConfig struct {
+	MinLevel  int  `yaml:"min_level"`
+	MaxLevel  int  `yaml:"max_level"`
+	Numbering bool `yaml:"numbering"`
+	Expanded  bool `yaml:"expanded"`
+	ScrollSpy bool `yaml:"scroll_spy"`
+}
+
+type PaletteFile struct {
+	Palette     *gen.PaletteConfig `yaml:"palette"`
+	DarkPalette *gen.PaletteConfig `yaml:"dark_palette"`
+}
+
+type ImageConfig struct {
+	Widths  []int  `yaml:"widths"`
+	Sizes   string `yaml:"sizes"`
+	Quality int    `yaml:"quality"`
+	WebP    bool   `yaml:"webp"`
+}
+
+type BlogConfig struct {
+	Dir      string `yaml:"dir"`
+	Path     string `yaml:"path"`
+	PageSize int    `yaml:"page_size"`
+	Title    string `yaml:"title"`
+}
+
+type FeedConfig struct {
+	Title string `yaml:"title"`
+	// Limit and TagLimit are the max number of items of the site feed and
+	// of the tag feeds, no limit if 0.
+	Limit    int `yaml:"limit"`
+	TagLimit int `yaml:"tag_limit"`
+	// FullContent puts the generated pages into the feeds, instead of the
+	// page summaries.
+	FullContent bool `yaml:"full_content"`
 }
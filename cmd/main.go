@@ -4,14 +4,19 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/iamjinlei/proteus/gen"
+	"github.com/iamjinlei/proteus/gen/markdown"
 )
 
 const (
@@ -20,6 +25,17 @@ const (
 
 	dirPermMode  = 0755
 	filePermMode = 0644
+
+	// themesDir holds the themes selectable by name, themeOverrideDir the
+	// files overriding the ones of the selected theme.
+	themesDir        = "themes"
+	themeOverrideDir = "theme"
+
+	// Paths of the RSS and Atom feeds, tag feeds are at the same paths in
+	// the tagsFeedDir/<tag> dir.
+	rssFeedPath  = "/feed.xml"
+	atomFeedPath = "/atom.xml"
+	tagsFeedDir  = "/tags"
 )
 
 func main() {
@@ -53,10 +69,65 @@ func main() {
 		return
 	}
 	fmt.Printf("yaml domain = %v\n", cfg.Domain)
-	g, err := gen.NewHtml(gen.DefaultConfig(
+	gCfg := gen.DefaultConfig(
 		cfg.Domain,
 		htmlSuffix,
-	))
+	)
+	gCfg.PrevNext = cfg.PrevNext
+	if cfg.ToC.MinLevel > 0 {
+		gCfg.ToC.MinLevel = cfg.ToC.MinLevel
+	}
+	if cfg.ToC.MaxLevel > 0 {
+		gCfg.ToC.MaxLevel = cfg.ToC.MaxLevel
+	}
+	gCfg.ToC.Numbering = cfg.ToC.Numbering
+	gCfg.ToC.Expanded = cfg.ToC.Expanded
+	gCfg.ToC.ScrollSpy = cfg.ToC.ScrollSpy
+	gCfg.LinkProviders = cfg.LinkProviders
+	gCfg.KeywordTypes = cfg.KeywordTypes
+	gCfg.GlossaryPath = cfg.Glossary
+	if cfg.Math != "" {
+		gCfg.Math = markdown.MathMode(cfg.Math)
+	}
+	gCfg.Diagrams = cfg.Diagrams
+	gCfg.DiagramCacheDir = cfg.DiagramCache
+	gCfg.PlantUMLServer = cfg.PlantUMLServer
+	gCfg.MarkdownExtensions = cfg.Extensions
+	gCfg.Lightbox = cfg.Lightbox
+	gCfg.Fingerprint = cfg.Fingerprint
+	gCfg.SharedStatic = cfg.SharedStatic
+	gCfg.StaticPath = cfg.StaticPath
+	gCfg.Minify = cfg.Minify
+	gCfg.DarkMode = cfg.DarkMode
+	gCfg.ThemeToggle = cfg.ThemeToggle
+	applyImageConfig(cfg.Images, &gCfg)
+	if cfg.Blog != nil {
+		blog := gen.DefaultBlogConfig
+		blog.Dir = cfg.Blog.Dir
+		blog.Path = cfg.Blog.Path
+		if cfg.Blog.PageSize > 0 {
+			blog.PageSize = cfg.Blog.PageSize
+		}
+		if cfg.Blog.Title != "" {
+			blog.Title = cfg.Blog.Title
+		}
+		gCfg.Blog = &blog
+	}
+	for _, path := range cfg.Order {
+		relPath, _ := outputRelPath(cfg, path)
+		gCfg.PageOrder = append(gCfg.PageOrder, relPath)
+	}
+	if cfg.Theme != "" {
+		if err := loadTheme(srcDir, cfg.Theme, &gCfg); err != nil {
+			fmt.Printf("Error loading theme %v: %v\n", cfg.Theme, err)
+			return
+		}
+	}
+	if err := loadPalettes(srcDir, cfg, &gCfg); err != nil {
+		fmt.Printf("Error loading palettes: %v\n", err)
+		return
+	}
+	g, err := gen.NewHtml(gCfg)
 	if err != nil {
 		fmt.Printf("Error creating html renderer: %v\n", err)
 		return
@@ -65,46 +136,44 @@ func main() {
 	if *genFlag {
 		dstDir := filepath.Clean(*dstFlag)
 		sm := gen.NewSitemap(cfg.Domain)
-
-		refQueue := []string{
-			cfg.Entry,
+		var feed *gen.Feed
+		if cfg.Feed != nil && cfg.Domain != "" {
+			feed = gen.NewFeed(
+				cfg.Domain,
+				cfg.Feed.Title,
+				cfg.Feed.Limit,
+				cfg.Feed.FullContent,
+			)
 		}
-		for path, _ := range cfg.Assets {
-			refQueue = append(refQueue, path)
+
+		// All pages are discovered before any of them is generated so that
+		// site wide components see the complete site.
+		jobs, err := discover(srcDir, cfg, g)
+		if err != nil {
+			fmt.Printf("Error discovering pages: %v\n", err)
+			return
 		}
 
+		// Pages without lastmod page config take the time of their last
+		// commit, or of their last change if not committed.
+		commitTimes := gitCommitTimes(srcDir)
+
 		mdCnt := 0
+		// Page sizes before and after minification.
+		rawSize, size := 0, 0
 		dirSeen := map[string]bool{}
-		for len(refQueue) > 0 {
-			// relPath is the path relative to the source repo dir.
-			relPath := refQueue[0]
-			refQueue = refQueue[1:]
-			isMarkdown := strings.HasSuffix(relPath, mdSuffix)
-
-			src := filepath.Join(srcDir, relPath)
-			dst := filepath.Join(dstDir, relPath)
-			switch relPath {
-			case cfg.Entry:
-				dst = filepath.Join(dstDir, "index.html")
-			default:
-				if v := cfg.Assets[relPath]; v != "" {
-					dst = filepath.Join(dstDir, v)
-					isMarkdown = false
-				}
-
-				if isMarkdown {
-					dst += htmlSuffix
+		for _, j := range jobs {
+			dst := filepath.Join(dstDir, j.relPath)
+			if j.isMarkdown {
+				if j.meta.LastMod.IsZero() {
+					j.meta.LastMod = lastModTime(j.src, commitTimes)
 				}
-			}
-
-			relPath = dst[len(dstDir):]
-			if isMarkdown {
-				sm.Add(relPath)
-			} else if !*forceFlag && !updateRequired(src, dst) {
+				sm.AddPage(j.meta)
+			} else if !*forceFlag && !updateRequired(j.src, dst) {
 				continue
 			}
 
-			fmt.Printf("Processing %v -> %v\n", src, dst)
+			fmt.Printf("Processing %v -> %v\n", j.src, dst)
 
 			dstDir := filepath.Dir(dst)
 			if !dirSeen[dstDir] {
@@ -115,111 +184,160 @@ func main() {
 				dirSeen[dstDir] = true
 			}
 
-			data, err := os.ReadFile(src)
-			if err != nil {
-				fmt.Printf("Error reading source file %v: %v\n", src, err)
-				return
+			data := j.data
+			if data == nil {
+				if data, err = os.ReadFile(j.src); err != nil {
+					fmt.Printf("Error reading source file %v: %v\n", j.src, err)
+					return
+				}
 			}
 
-			if isMarkdown {
-				page, err := g.Gen(relPath, data)
+			if j.isMarkdown {
+				page, err := g.Gen(j.relPath, data)
 				if err != nil {
 					fmt.Printf("Error generating HTML page: %v\n", err)
 					return
 				}
 
-				relDir := filepath.Dir(relPath)
-				for _, ref := range page.InternalRefs {
-					// If the link path referenced in the markdown is relative
-					// to the markdown file location, update it to be relative
-					// to the src repo location before pushing into queue.
-					if relDir != "" && !strings.HasPrefix(ref, relDir) {
-						ref = filepath.Join(relDir, ref)
-					}
-					refQueue = append(refQueue, ref)
-				}
-
 				data = page.Html
+				if feed != nil {
+					feed.Add(j.meta, page.Content)
+				}
 				mdCnt++
+				rawSize += page.RawSize
+				size += len(page.Html)
 			}
 
 			if err := os.WriteFile(dst, data, filePermMode); err != nil {
-				fmt.Printf("Error writing destination file %v: %v\n", src, err)
+				fmt.Printf("Error writing destination file %v: %v\n", dst, err)
 				return
 			}
 		}
 
-		if cfg.EnableSitemap && cfg.Domain != "" {
-			data, err := sm.Gen()
+		static := g.Static()
+		if gCfg.Theme != nil {
+			files, err := gCfg.Theme.Static()
 			if err != nil {
-				fmt.Printf("Error generating sitemap file: %v\n", err)
+				fmt.Printf("Error reading theme static files: %v\n", err)
+				return
+			}
+			static = append(static, files...)
+		}
+		for _, f := range static {
+			dst := filepath.Join(dstDir, f.RelPath)
+			if err := os.MkdirAll(filepath.Dir(dst), dirPermMode); err != nil {
+				fmt.Printf("Error creating directory %v: %v\n", filepath.Dir(dst), err)
 				return
 			}
-			if err := os.WriteFile(
-				filepath.Join(dstDir, "sitemap.xml"),
-				data,
-				filePermMode,
-			); err != nil {
-				fmt.Printf("Error writing sitemap file: %v\n", err)
+			if err := os.WriteFile(dst, f.Data, filePermMode); err != nil {
+				fmt.Printf("Error writing static file %v: %v\n", dst, err)
 				return
 			}
 		}
 
-		fmt.Printf("Total markdown files processed: %v\n", mdCnt)
-	} else {
-		rassets := map[string]string{}
-		for from, to := range cfg.Assets {
-			rassets[to] = from
-		}
-
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			path := r.URL.Path
-			fmt.Printf("Path = %v\n", r.URL.Path)
-			switch path {
-			case "", "/", "/index.html":
-				path = cfg.Entry + htmlSuffix
-			default:
-				if v := rassets[path]; v != "" {
-					path = v
-				}
+		if cfg.Glossary != "" {
+			page, err := g.GenGlossary()
+			if err != nil {
+				fmt.Printf("Error generating glossary page: %v\n", err)
+				return
+			}
+			rawSize += page.RawSize
+			size += len(page.Html)
+			dst := filepath.Join(dstDir, cfg.Glossary)
+			if err := os.MkdirAll(filepath.Dir(dst), dirPermMode); err != nil {
+				fmt.Printf("Error creating directory %v: %v\n", filepath.Dir(dst), err)
+				return
 			}
+			if err := os.WriteFile(dst, page.Html, filePermMode); err != nil {
+				fmt.Printf("Error writing glossary file %v: %v\n", dst, err)
+				return
+			}
+			sm.Add(cfg.Glossary)
+		}
 
-			directCopy := true
-			if strings.HasSuffix(path, htmlSuffix) {
-				directCopy = false
-				path = path[:len(path)-len(htmlSuffix)]
-				if !strings.HasSuffix(path, mdSuffix) {
-					path += mdSuffix
-				}
+		blogPages, err := g.GenBlog()
+		if err != nil {
+			fmt.Printf("Error generating blog pages: %v\n", err)
+			return
+		}
+		for _, p := range blogPages {
+			rawSize += p.RawSize
+			size += len(p.Html)
+			dst := filepath.Join(dstDir, p.RelPath)
+			if err := os.MkdirAll(filepath.Dir(dst), dirPermMode); err != nil {
+				fmt.Printf("Error creating directory %v: %v\n", filepath.Dir(dst), err)
+				return
+			}
+			if err := os.WriteFile(dst, p.Html, filePermMode); err != nil {
+				fmt.Printf("Error writing blog file %v: %v\n", dst, err)
+				return
 			}
+			sm.Add(p.RelPath)
+		}
 
-			path = filepath.Clean(filepath.Join(srcDir, path))
-			if !strings.HasPrefix(path, srcDir) || isHidden(path) {
-				// Serve 404
-				w.Write([]byte("Not Found"))
+		if feed != nil {
+			if err := writeFeeds(dstDir, cfg.Feed, feed); err != nil {
+				fmt.Printf("Error writing feeds: %v\n", err)
 				return
 			}
+		}
 
-			data, err := os.ReadFile(path)
+		if cfg.EnableSitemap && cfg.Domain != "" {
+			files, err := sm.Gen()
 			if err != nil {
-				// TODO(lei): serve 404
-				w.Write([]byte("Not Found"))
-				fmt.Printf("Error reading file %v: %v\n", path, err)
+				fmt.Printf("Error generating sitemap file: %v\n", err)
 				return
 			}
-
-			if directCopy {
-				w.Write(data)
-			} else {
-				page, err := g.Gen(path, data)
-				if err != nil {
-					w.Write([]byte(fmt.Sprintf("Error generating html page: %v", err)))
-				} else {
-					fmt.Printf("Transformed, %v bytes\n", len(page.Html))
-					w.Write(page.Html)
+			for _, f := range files {
+				if err := os.WriteFile(
+					filepath.Join(dstDir, f.RelPath),
+					f.Data,
+					filePermMode,
+				); err != nil {
+					fmt.Printf("Error writing sitemap file: %v\n", err)
+					return
 				}
 			}
-		})
+		}
+
+		fmt.Printf("Total markdown files processed: %v\n", mdCnt)
+		if cfg.Minify && rawSize > 0 {
+			fmt.Printf(
+				"Minified HTML pages: %v -> %v bytes, saved %.1f%%\n",
+				rawSize,
+				size,
+				float64(rawSize-size)*100/float64(rawSize),
+			)
+		}
+	} else {
+		jobs, err := discover(srcDir, cfg, g)
+		if err != nil {
+			fmt.Printf("Error discovering pages: %v\n", err)
+			return
+		}
+
+		// Generated files, e.g., resized images, have no source file.
+		generated := map[string][]byte{}
+		for _, j := range jobs {
+			if j.generated {
+				generated[j.relPath] = j.data
+			}
+		}
+		for _, f := range g.Static() {
+			generated[f.RelPath] = f.Data
+		}
+		if gCfg.Theme != nil {
+			files, err := gCfg.Theme.Static()
+			if err != nil {
+				fmt.Printf("Error reading theme static files: %v\n", err)
+				return
+			}
+			for _, f := range files {
+				generated[f.RelPath] = f.Data
+			}
+		}
+
+		http.Handle("/", newServer(srcDir, cfg, gCfg, g, generated))
 
 		fmt.Printf("Start serving at http://localhost:8000\n")
 		if err := http.ListenAndServe(":8000", nil); err != nil {
@@ -229,6 +347,204 @@ func main() {
 	}
 }
 
+type job struct {
+	// src is the source file path.
+	src string
+	// relPath is the output file path relative to the destination dir.
+	relPath    string
+	isMarkdown bool
+	// data holds the content of a markdown file or image read during
+	// discovery, or the content of a generated file.
+	data []byte
+	// generated is true for files produced by the build, e.g., resized
+	// images, or published under another name, e.g., fingerprinted assets.
+	// src is the file they are derived from.
+	generated bool
+	// meta is the scanned metadata of a markdown page.
+	meta *gen.PageMeta
+}
+
+// discover walks the internal references starting from the entry page and
+// scans every markdown page found on the way.
+func discover(srcDir string, cfg Config, g *gen.Html) ([]*job, error) {
+	refQueue := []string{
+		cfg.Entry,
+	}
+	for path, _ := range cfg.Assets {
+		refQueue = append(refQueue, path)
+	}
+	if cfg.Blog != nil {
+		// Posts are listed by the blog pages rather than linked from the
+		// entry page.
+		posts, err := findPosts(srcDir, cfg.Blog.Dir)
+		if err != nil {
+			return nil, err
+		}
+		refQueue = append(refQueue, posts...)
+	}
+
+	var jobs []*job
+	seen := map[string]bool{}
+	for len(refQueue) > 0 {
+		// ref is the path relative to the source repo dir.
+		ref := filepath.Clean(refQueue[0])
+		refQueue = refQueue[1:]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		relPath, isMarkdown := outputRelPath(cfg, ref)
+		j := &job{
+			src:        filepath.Join(srcDir, ref),
+			relPath:    relPath,
+			isMarkdown: isMarkdown,
+		}
+		jobs = append(jobs, j)
+		if !isMarkdown && !gen.IsImage(ref) && !cfg.Fingerprint {
+			continue
+		}
+
+		data, err := os.ReadFile(j.src)
+		if err != nil {
+			return nil, err
+		}
+		j.data = data
+
+		if !isMarkdown {
+			// Assets are registered before any page is generated, so
+			// that pages refer to their final names, sizes and resized
+			// variants.
+			if fp := g.AddAsset(relPath, data); fp != relPath {
+				j.relPath = fp
+				j.generated = true
+			}
+
+			variants, err := g.AddImage(relPath, data)
+			if err != nil {
+				return nil, fmt.Errorf("image %v: %w", ref, err)
+			}
+			for _, v := range variants {
+				jobs = append(jobs, &job{
+					src:       j.src,
+					relPath:   v.RelPath,
+					data:      v.Data,
+					generated: true,
+				})
+			}
+			continue
+		}
+
+		page, err := g.Scan(relPath, data)
+		if err != nil {
+			return nil, err
+		}
+		j.meta = page
+
+		relDir := filepath.Dir(ref)
+		for _, ref := range page.InternalRefs {
+			// If the link path referenced in the markdown is relative
+			// to the markdown file location, update it to be relative
+			// to the src repo location before pushing into queue.
+			if relDir != "" && !strings.HasPrefix(ref, relDir) {
+				ref = filepath.Join(relDir, ref)
+			}
+			refQueue = append(refQueue, ref)
+		}
+	}
+
+	return jobs, nil
+}
+
+// writeFeeds writes the site feed and the feeds of every tag.
+func writeFeeds(dstDir string, cfg *FeedConfig, feed *gen.Feed) error {
+	feeds := map[string]*gen.Feed{
+		"/": feed,
+	}
+	for _, t := range feed.Tags() {
+		feeds[filepath.Join(tagsFeedDir, gen.TagSlug(t))] = feed.TagFeed(
+			t,
+			fmt.Sprintf("%s: %s", cfg.Title, t),
+			cfg.TagLimit,
+		)
+	}
+
+	for dir, f := range feeds {
+		for p, genFeed := range map[string]func(string) ([]byte, error){
+			filepath.Join(dir, rssFeedPath):  f.GenRss,
+			filepath.Join(dir, atomFeedPath): f.GenAtom,
+		} {
+			data, err := genFeed(p)
+			if err != nil {
+				return err
+			}
+
+			dst := filepath.Join(dstDir, p)
+			if err := os.MkdirAll(filepath.Dir(dst), dirPermMode); err != nil {
+				return err
+			}
+			if err := os.WriteFile(dst, data, filePermMode); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findPosts returns the markdown files in the posts dir, relative to the
+// source dir.
+func findPosts(srcDir string, dir string) ([]string, error) {
+	var posts []string
+	err := filepath.WalkDir(
+		filepath.Join(srcDir, dir),
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if isHidden(path) && path != filepath.Join(srcDir, dir) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !strings.HasSuffix(path, mdSuffix) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(srcDir, path)
+			if err != nil {
+				return err
+			}
+			posts = append(posts, filepath.Join("/", rel))
+			return nil
+		},
+	)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return posts, err
+}
+
+// outputRelPath maps a path relative to the source dir to the path of the
+// generated file relative to the destination dir.
+func outputRelPath(cfg Config, relPath string) (string, bool) {
+	if relPath == cfg.Entry {
+		return "/index.html", true
+	}
+
+	if v := cfg.Assets[relPath]; v != "" {
+		return v, false
+	}
+
+	if strings.HasSuffix(relPath, mdSuffix) {
+		return relPath + htmlSuffix, true
+	}
+
+	return relPath, false
+}
+
 func fileExists(path string) bool {
 	fi, err := os.Stat(path)
 	return err == nil && !fi.IsDir()
@@ -239,6 +555,69 @@ func isHidden(path string) bool {
 	return base[0] == '.'
 }
 
+// gitCommitTimes maps the files in srcDir to the time of their last commit.
+// It is empty if srcDir is not in a git repo.
+func gitCommitTimes(srcDir string) map[string]time.Time {
+	out, err := exec.Command(
+		"git",
+		"-C",
+		srcDir,
+		// Paths are only quoted if they contain control characters, quotes
+		// or backslashes, not for non ASCII characters.
+		"-c",
+		"core.quotePath=false",
+		"log",
+		"--format=%x00%cI",
+		"--name-only",
+		"--relative",
+	).Output()
+	if err != nil {
+		return nil
+	}
+
+	return parseGitLog(srcDir, string(out))
+}
+
+// parseGitLog parses the output of gitCommitTimes.
+func parseGitLog(srcDir string, out string) map[string]time.Time {
+	times := map[string]time.Time{}
+	var t time.Time
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case line == "":
+		case line[0] == 0:
+			t, _ = time.Parse(time.RFC3339, line[1:])
+		default:
+			// Quoted paths use C style escapes, which are valid in Go.
+			if line[0] == '"' {
+				if p, err := strconv.Unquote(line); err == nil {
+					line = p
+				}
+			}
+			// Commits are listed the latest first.
+			path := filepath.Join(srcDir, line)
+			if _, found := times[path]; !found && !t.IsZero() {
+				times[path] = t
+			}
+		}
+	}
+	return times
+}
+
+// lastModTime returns the time of the last commit of a file, or its
+// modification time if not committed.
+func lastModTime(path string, commitTimes map[string]time.Time) time.Time {
+	if t, found := commitTimes[path]; found {
+		return t
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
+
 func updateRequired(src, dst string) bool {
 	dstFi, err := os.Stat(dst)
 	if err != nil {
@@ -273,6 +652,95 @@ func loadConfig(srcDir, path string) (Config, error) {
 		assets[filepath.Join("/", from)] = filepath.Join("/", to)
 	}
 	cfg.Assets = assets
+	for i, path := range cfg.Order {
+		cfg.Order[i] = filepath.Join("/", path)
+	}
+	if cfg.Glossary != "" {
+		cfg.Glossary = filepath.Join("/", cfg.Glossary)
+	}
+	if b := cfg.Blog; b != nil {
+		if b.Dir == "" {
+			b.Dir = gen.DefaultBlogConfig.Dir
+		}
+		if b.Path == "" {
+			b.Path = gen.DefaultBlogConfig.Path
+		}
+		b.Dir = filepath.Join("/", b.Dir)
+		b.Path = filepath.Join("/", b.Path)
+	}
+	if cfg.Feed != nil && cfg.Feed.Title == "" {
+		cfg.Feed.Title = cfg.Domain
+	}
 
 	return cfg, nil
 }
+
+// loadTheme loads the named theme from the themes dir of the site. Files in
+// the theme dir of the site override the ones of the theme.
+// applyImageConfig overrides the image defaults of gCfg with the ones set
+// in cfg.
+func applyImageConfig(cfg *ImageConfig, gCfg *gen.Config) {
+	if cfg == nil {
+		return
+	}
+	if len(cfg.Widths) > 0 {
+		gCfg.Image.Widths = cfg.Widths
+	}
+	if cfg.Sizes != "" {
+		gCfg.Image.Sizes = cfg.Sizes
+	}
+	if cfg.Quality > 0 {
+		gCfg.Image.Quality = cfg.Quality
+	}
+	gCfg.Image.WebP = cfg.WebP
+}
+
+func loadTheme(srcDir string, name string, gCfg *gen.Config) error {
+	dir := filepath.Join(srcDir, themesDir, name)
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return fmt.Errorf("theme dir %v not found", dir)
+	}
+
+	theme, err := gen.LoadTheme(
+		os.DirFS(filepath.Join(srcDir, themeOverrideDir)),
+		os.DirFS(dir),
+	)
+	if err != nil {
+		return err
+	}
+	return theme.Apply(gCfg)
+}
+
+// loadPalettes applies the palette configs of the palette file, and then
+// the ones of the config file, to the palettes of gCfg.
+func loadPalettes(srcDir string, cfg Config, gCfg *gen.Config) error {
+	var pCfgs []PaletteFile
+	if cfg.PaletteFile != "" {
+		data, err := os.ReadFile(filepath.Join(srcDir, cfg.PaletteFile))
+		if err != nil {
+			return err
+		}
+
+		var f PaletteFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return fmt.Errorf("%v: %w", cfg.PaletteFile, err)
+		}
+		pCfgs = append(pCfgs, f)
+	}
+	pCfgs = append(pCfgs, PaletteFile{
+		Palette:     cfg.Palette,
+		DarkPalette: cfg.DarkPalette,
+	})
+
+	for _, f := range pCfgs {
+		var err error
+		if gCfg.Palette, err = f.Palette.Apply(gCfg.Palette); err != nil {
+			return fmt.Errorf("palette: %w", err)
+		}
+		if gCfg.DarkPalette, err = f.DarkPalette.Apply(gCfg.DarkPalette); err != nil {
+			return fmt.Errorf("dark_palette: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/iamjinlei/proteus/gen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitLog(t *testing.T) {
+	// Captured with core.quotePath=false, which still quotes paths with
+	// quotes.
+	out := "\x002024-03-04T05:06:07+00:00\n" +
+		"\n" +
+		"día.md\n" +
+		"plain.md\n" +
+		"\x002024-01-02T03:04:05+00:00\n" +
+		"\n" +
+		"día.md\n" +
+		"\"q\\\"uote.md\"\n" +
+		"\"d\\303\\255a/b.md\"\n"
+
+	times := parseGitLog("src", out)
+	latest := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.Len(t, times, 4)
+	require.True(t, latest.Equal(times["src/día.md"]))
+	require.True(t, latest.Equal(times["src/plain.md"]))
+	require.True(t, first.Equal(times[`src/q"uote.md`]))
+	require.True(t, first.Equal(times["src/día/b.md"]))
+}
+
+func TestApplyImageConfig(t *testing.T) {
+	gCfg := gen.DefaultConfig("", ".html")
+	applyImageConfig(nil, &gCfg)
+	require.Equal(t, gen.DefaultImageConfig, gCfg.Image)
+
+	// Unset fields keep the defaults.
+	applyImageConfig(&ImageConfig{WebP: true}, &gCfg)
+	require.Equal(t, gen.DefaultImageConfig.Widths, gCfg.Image.Widths)
+	require.Equal(t, gen.DefaultImageConfig.Sizes, gCfg.Image.Sizes)
+	require.Equal(t, gen.DefaultImageConfig.Quality, gCfg.Image.Quality)
+	require.True(t, gCfg.Image.WebP)
+
+	applyImageConfig(&ImageConfig{
+		Widths:  []int{320, 640},
+		Sizes:   "100vw",
+		Quality: 70,
+	}, &gCfg)
+	require.Equal(t, gen.ImageConfig{
+		Widths:  []int{320, 640},
+		Sizes:   "100vw",
+		Quality: 70,
+	}, gCfg.Image)
+}
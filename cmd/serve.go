@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/iamjinlei/proteus/gen"
+)
+
+// server serves the site from the source directory, generating pages on
+// request.
+type server struct {
+	srcDir string
+	cfg    Config
+	gCfg   gen.Config
+	g      *gen.Html
+	// generated are the files without a source file, e.g., resized images,
+	// by path.
+	generated map[string][]byte
+	// rassets maps the paths of copied assets to their source paths.
+	rassets map[string]string
+	// mu serializes requests, as gen.Html is not safe for concurrent use.
+	mu sync.Mutex
+}
+
+func newServer(
+	srcDir string,
+	cfg Config,
+	gCfg gen.Config,
+	g *gen.Html,
+	generated map[string][]byte,
+) *server {
+	rassets := map[string]string{}
+	for from, to := range cfg.Assets {
+		rassets[to] = from
+	}
+
+	return &server{
+		srcDir:    srcDir,
+		cfg:       cfg,
+		gCfg:      gCfg,
+		g:         g,
+		generated: generated,
+		rassets:   rassets,
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := r.URL.Path
+	fmt.Printf("Path = %v\n", r.URL.Path)
+	if data := s.generated[path]; data != nil {
+		// Stylesheets are not applied unless served as text/css.
+		if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+			w.Header().Set("Content-Type", t)
+		}
+		w.Write(data)
+		return
+	}
+	if s.cfg.Glossary != "" && path == s.cfg.Glossary {
+		page, err := s.g.GenGlossary()
+		if err != nil {
+			w.Write([]byte(fmt.Sprintf("Error generating glossary page: %v", err)))
+		} else {
+			w.Write(page.Html)
+		}
+		return
+	}
+	if s.gCfg.Blog != nil && strings.HasPrefix(path, s.gCfg.Blog.Path+"/") {
+		pages, err := s.g.GenBlog()
+		if err != nil {
+			w.Write([]byte(fmt.Sprintf("Error generating blog pages: %v", err)))
+			return
+		}
+		for _, p := range pages {
+			if p.RelPath == path {
+				w.Write(p.Html)
+				return
+			}
+		}
+	}
+
+	switch path {
+	case "", "/", "/index.html":
+		path = s.cfg.Entry + htmlSuffix
+	default:
+		if v := s.rassets[path]; v != "" {
+			path = v
+		}
+	}
+
+	directCopy := true
+	if strings.HasSuffix(path, htmlSuffix) {
+		directCopy = false
+		path = path[:len(path)-len(htmlSuffix)]
+		if !strings.HasSuffix(path, mdSuffix) {
+			path += mdSuffix
+		}
+	}
+
+	relPath, _ := outputRelPath(s.cfg, path)
+	path = filepath.Clean(filepath.Join(s.srcDir, path))
+	if !strings.HasPrefix(path, s.srcDir) || isHidden(path) {
+		// Serve 404
+		w.Write([]byte("Not Found"))
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// TODO(lei): serve 404
+		w.Write([]byte("Not Found"))
+		fmt.Printf("Error reading file %v: %v\n", path, err)
+		return
+	}
+
+	if directCopy {
+		w.Write(data)
+	} else {
+		page, err := s.g.Gen(relPath, data)
+		if err != nil {
+			w.Write([]byte(fmt.Sprintf("Error generating html page: %v", err)))
+		} else {
+			fmt.Printf("Transformed, %v bytes\n", len(page.Html))
+			w.Write(page.Html)
+		}
+	}
+}
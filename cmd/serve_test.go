@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen"
+)
+
+func TestServeConcurrent(t *testing.T) {
+	srcDir := t.TempDir()
+	for name, src := range map[string]string{
+		"config.yaml": "entry: index.md\nblog:\n  dir: posts\n",
+		"index.md":    "# Home\n\n[A](a.md) [B](b.md)\n",
+		"a.md":        "# Page A\n\n[B](b.md)\n",
+		"b.md":        "# Page B\n\n[A](a.md)\n",
+		"posts/p.md":  "<!---\ndate: 2024-01-02\n--->\n# Post P\n",
+	} {
+		path := filepath.Join(srcDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), dirPermMode))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0644))
+	}
+
+	cfg, err := loadConfig(srcDir, filepath.Join(srcDir, "config.yaml"))
+	require.NoError(t, err)
+	gCfg := gen.DefaultConfig("", htmlSuffix)
+	blog := gen.DefaultBlogConfig
+	blog.Dir = cfg.Blog.Dir
+	gCfg.Blog = &blog
+	g, err := gen.NewHtml(gCfg)
+	require.NoError(t, err)
+	_, err = discover(srcDir, cfg, g)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(newServer(srcDir, cfg, gCfg, g, nil))
+	defer srv.Close()
+
+	pages := map[string]string{
+		"/":                "Home",
+		"/a.md.html":       "Page A",
+		"/b.md.html":       "Page B",
+		"/posts/p.md.html": "Post P",
+		"/blog/index.html": "Post P",
+	}
+	// Run with -race, requests for pages and blog listings are served
+	// concurrently.
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		for path, text := range pages {
+			wg.Add(1)
+			go func(path, text string) {
+				defer wg.Done()
+				resp, err := http.Get(srv.URL + path)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Contains(t, string(body), text, path)
+			}(path, text)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,59 @@
+package gen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	fingerprintLen = 6
+)
+
+// fingerprintPath inserts the content hash of a file into its name, e.g.,
+// "/img/gopher.png" becomes "/img/gopher.3f9a1c.png".
+func fingerprintPath(relPath string, data []byte) string {
+	sum := sha256.Sum256(data)
+	ext := filepath.Ext(relPath)
+	return strings.TrimSuffix(relPath, ext) + "." +
+		hex.EncodeToString(sum[:])[:fingerprintLen] + ext
+}
+
+// AddAsset registers a file published with the site and returns the path to
+// write it to. With Config.Fingerprint, the path carries the content hash of
+// the file and pages generated afterwards refer to the asset by it.
+func (h *Html) AddAsset(relPath string, data []byte) string {
+	if !h.cfg.Fingerprint {
+		return relPath
+	}
+
+	fp := fingerprintPath(relPath, data)
+	h.assets[relPath] = fp
+	return fp
+}
+
+// resolveRef turns a reference in the page at relPath into a path relative
+// to the site root.
+func resolveRef(relPath string, ref string) string {
+	if !filepath.IsAbs(ref) {
+		ref = filepath.Join(filepath.Dir(relPath), ref)
+	}
+	return filepath.Clean(ref)
+}
+
+// refRewriter rewrites asset references of the page at relPath to their
+// fingerprinted names, keeping references relative if they are.
+func (h *Html) refRewriter(relPath string) markdown.RefRewriter {
+	return func(ref string) string {
+		fp := h.assets[resolveRef(relPath, ref)]
+		if fp == "" {
+			return ref
+		}
+
+		base := filepath.Base(ref)
+		return ref[:len(ref)-len(base)] + filepath.Base(fp)
+	}
+}
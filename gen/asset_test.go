@@ -0,0 +1,43 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprintAssets(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "/img/a.png", h.AddAsset("/img/a.png", []byte("a")))
+
+	cfg.Fingerprint = true
+	h, err = NewHtml(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "/img/a.ca9781.png", h.AddAsset("/img/a.png", []byte("a")))
+	require.Equal(t, "/doc/b.3e23e8.pdf", h.AddAsset("/doc/b.pdf", []byte("b")))
+
+	page, err := h.Gen("/guide/page.md.html", []byte(`<!---
+banner: /img/a.png
+--->
+![a](../img/a.png) [b](/doc/b.pdf) [c](c.md) <img src="../img/a.png">
+
+<ins type="book_bib" title="T" cover="../img/a.png" link="l" author="A"/>
+`))
+	require.NoError(t, err)
+
+	html := string(page.Html)
+	require.Contains(t, html, `<img class="banner" src="/img/a.ca9781.png">`)
+	require.Contains(t, html, `src="../img/a.ca9781.png" alt="a"`)
+	require.Contains(t, html, `<a href="/doc/b.3e23e8.pdf">b</a>`)
+	require.Contains(t, html, `<a href="c.md.html">c</a>`)
+	require.Contains(t, html, `<img src="../img/a.ca9781.png" loading="lazy"/>`)
+	require.Contains(t, html, `<a href="l"><img src="../img/a.ca9781.png"></a>`)
+	require.NotContains(t, html, `a.png"`)
+	require.ElementsMatch(
+		t,
+		[]string{"../img/a.png", "/doc/b.pdf", "c.md", "../img/a.png", "/img/a.png"},
+		page.InternalRefs,
+	)
+}
@@ -0,0 +1,362 @@
+package gen
+
+import (
+	"fmt"
+	"html/template"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	defaultBlogCss = `
+.blog h1 {
+	border-bottom: 1px solid {{ .Palette.LightGray }};
+}
+.blog_post {
+	margin: 2em 0;
+}
+.blog_post h2 {
+	margin-bottom: 0.2em;
+}
+.blog_post h2 a {
+	text-decoration: none;
+	color: {{ .Palette.Foreground }};
+}
+.blog_meta {
+	font-size: 0.8em;
+	color: {{ .Palette.DarkGray }};
+}
+.blog_tag {
+	margin-left: 0.6em;
+	padding: 0 0.4em;
+	border-radius: 4px;
+	text-decoration: none;
+	color: {{ .Palette.Foreground }};
+	background-color: {{ .Palette.LightGray }};
+}
+.blog_pages {
+	display: grid;
+	grid-template-columns: 1fr auto 1fr;
+	margin-top: 3em;
+	padding-top: 1em;
+	border-top: 1px solid {{ .Palette.LightGray }};
+}
+.blog_pages a {
+	text-decoration: none;
+	color: {{ .Palette.Foreground }};
+}
+.blog_pages .next {
+	text-align: right;
+}
+`
+)
+
+// BlogConfig controls the listing pages of dated posts.
+type BlogConfig struct {
+	// Dir is the directory of the posts relative to the site root. Pages
+	// in it with a date in page config are posts.
+	Dir string
+	// Path is the directory of the listing pages relative to the site
+	// root. The index is at Path/index.html, tag pages at
+	// Path/tags/<tag>/index.html and archives at Path/<year>/index.html.
+	Path string
+	// PageSize is the number of posts per listing page.
+	PageSize int
+	Title    string
+}
+
+var (
+	DefaultBlogConfig = BlogConfig{
+		Dir:      "posts",
+		Path:     "/blog",
+		PageSize: 10,
+		Title:    "Blog",
+	}
+)
+
+// ListingPage is a page generated from the site content, e.g., a blog index
+// page.
+type ListingPage struct {
+	RelPath string
+	*Page
+}
+
+// listing is a paginated list of posts.
+type listing struct {
+	// dir is the directory of the listing pages.
+	dir   string
+	title string
+	posts []*PageMeta
+}
+
+func (l *listing) pagePath(idx int) string {
+	if idx == 0 {
+		return path.Join(l.dir, "index.html")
+	}
+	return path.Join(l.dir, "page", fmt.Sprintf("%d.html", idx+1))
+}
+
+// isPost checks if a page is a blog post.
+func (h *Html) isPost(p *PageMeta) bool {
+	if h.cfg.Blog == nil || p.Date.IsZero() {
+		return false
+	}
+	dir := path.Join("/", h.cfg.Blog.Dir) + "/"
+	return strings.HasPrefix(path.Join("/", p.RelPath), dir)
+}
+
+// posts returns the scanned posts, the latest first.
+func (h *Html) posts() []*PageMeta {
+	var posts []*PageMeta
+	for _, p := range h.site.all() {
+		if h.isPost(p) {
+			posts = append(posts, p)
+		}
+	}
+	sort.SliceStable(posts, func(i, j int) bool {
+		if !posts[i].Date.Equal(posts[j].Date) {
+			return posts[i].Date.After(posts[j].Date)
+		}
+		return posts[i].RelPath < posts[j].RelPath
+	})
+
+	return posts
+}
+
+// TagSlug turns a tag into a path element, e.g., of the blog tag pages,
+// see markdown.Slugify. Tags differing in case share a slug, and the slug
+// of a tag without letters or digits is empty.
+func TagSlug(tag string) string {
+	return markdown.Slugify(tag)
+}
+
+// uniqueTags returns the tags with distinct slugs, see TagSlug, keeping the
+// first of tags sharing a slug. Tags with an empty slug are dropped.
+func uniqueTags(tags []string) []string {
+	seen := map[string]bool{}
+	var res []string
+	for _, t := range tags {
+		slug := TagSlug(t)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		res = append(res, t)
+	}
+	return res
+}
+
+// listings returns the blog index, the tag pages and the year archives.
+func (h *Html) listings() []*listing {
+	cfg := h.cfg.Blog
+	posts := h.posts()
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ls := []*listing{
+		&listing{
+			dir:   path.Join("/", cfg.Path),
+			title: cfg.Title,
+			posts: posts,
+		},
+	}
+
+	tags := map[string]*listing{}
+	years := map[int]*listing{}
+	var tagOrder []string
+	var yearOrder []int
+	for _, p := range posts {
+		for _, t := range uniqueTags(p.Tags) {
+			slug := TagSlug(t)
+			l := tags[slug]
+			if l == nil {
+				l = &listing{
+					dir:   path.Join("/", cfg.Path, "tags", slug),
+					title: fmt.Sprintf("%s: %s", cfg.Title, t),
+				}
+				tags[slug] = l
+				tagOrder = append(tagOrder, slug)
+			}
+			l.posts = append(l.posts, p)
+		}
+
+		y := p.Date.Year()
+		l := years[y]
+		if l == nil {
+			l = &listing{
+				dir:   path.Join("/", cfg.Path, fmt.Sprintf("%d", y)),
+				title: fmt.Sprintf("%s: %d", cfg.Title, y),
+			}
+			years[y] = l
+			yearOrder = append(yearOrder, y)
+		}
+		l.posts = append(l.posts, p)
+	}
+
+	sort.Strings(tagOrder)
+	for _, t := range tagOrder {
+		ls = append(ls, tags[t])
+	}
+	for _, y := range yearOrder {
+		ls = append(ls, years[y])
+	}
+
+	return ls
+}
+
+// GenBlog generates the listing pages of the scanned posts, see
+// Config.Blog.
+func (h *Html) GenBlog() ([]*ListingPage, error) {
+	if h.cfg.Blog == nil {
+		return nil, nil
+	}
+	h.freezeStatic()
+
+	var pages []*ListingPage
+	for _, l := range h.listings() {
+		size := h.cfg.Blog.PageSize
+		if size <= 0 {
+			size = len(l.posts)
+		}
+		cnt := (len(l.posts) + size - 1) / size
+
+		for i := 0; i < cnt; i++ {
+			end := (i + 1) * size
+			if end > len(l.posts) {
+				end = len(l.posts)
+			}
+
+			relPath := l.pagePath(i)
+			page, err := h.renderPage(h.listingTemplateData(
+				relPath,
+				renderListing(l, l.posts[i*size:end], i, cnt, h.cfg.Blog.Path),
+			))
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, &ListingPage{
+				RelPath: relPath,
+				Page:    page,
+			})
+		}
+	}
+
+	return pages, nil
+}
+
+func (h *Html) listingTemplateData(relPath string, main *HtmlComponent) *TemplateData {
+	main.include(template.CSS(h.cfg.Palette.ReplaceIn(defaultBlogCss)), "")
+
+	return h.link(newTemplateData(
+		h.cfg.Domain,
+		relPath,
+		h.cfg.Palette,
+		&HtmlComponent{},
+		&HtmlComponent{},
+		main,
+		&HtmlComponent{},
+		&HtmlComponent{},
+		h.footer(h.defaultPageConfig()),
+	))
+}
+
+// renderListing renders page idx of cnt pages of a listing.
+func renderListing(
+	l *listing,
+	posts []*PageMeta,
+	idx int,
+	cnt int,
+	blogPath string,
+) *HtmlComponent {
+	b := &strings.Builder{}
+	fmt.Fprintf(
+		b,
+		`<div class="blog"><h1>%s</h1>`,
+		template.HTMLEscapeString(l.title),
+	)
+
+	for _, p := range posts {
+		fmt.Fprintf(
+			b,
+			`<article class="blog_post"><h2><a href="%s">%s</a></h2><div class="blog_meta"><time datetime="%s">%s</time>`,
+			path.Join("/", p.RelPath),
+			template.HTMLEscapeString(p.Title),
+			p.Date.Format("2006-01-02"),
+			p.Date.Format("Jan 2, 2006"),
+		)
+		for _, t := range uniqueTags(p.Tags) {
+			fmt.Fprintf(
+				b,
+				`<a class="blog_tag" href="%s">%s</a>`,
+				path.Join("/", blogPath, "tags", TagSlug(t), "index.html"),
+				template.HTMLEscapeString(t),
+			)
+		}
+		b.WriteString(`</div>`)
+		if p.Summary != "" {
+			fmt.Fprintf(
+				b,
+				`<p class="blog_summary">%s</p>`,
+				template.HTMLEscapeString(p.Summary),
+			)
+		}
+		b.WriteString(`</article>`)
+	}
+
+	if cnt > 1 {
+		newer, older := "<span></span>", "<span></span>"
+		if idx > 0 {
+			newer = fmt.Sprintf(`<a class="prev" href="%s">&larr; Newer</a>`, l.pagePath(idx-1))
+		}
+		if idx < cnt-1 {
+			older = fmt.Sprintf(`<a class="next" href="%s">Older &rarr;</a>`, l.pagePath(idx+1))
+		}
+		fmt.Fprintf(
+			b,
+			`<nav class="blog_pages">%s<span>Page %d of %d</span>%s</nav>`,
+			newer,
+			idx+1,
+			cnt,
+			older,
+		)
+	}
+	b.WriteString(`</div>`)
+
+	return &HtmlComponent{
+		Html: template.HTML(b.String()),
+	}
+}
+
+// firstParagraphText returns the text of the first paragraph of a page.
+func firstParagraphText(src template.HTML) string {
+	z := html.NewTokenizer(strings.NewReader(string(src)))
+	depth := 0
+	var b strings.Builder
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.TrimSpace(b.String())
+
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) == "p" {
+				depth++
+			}
+
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "p" && depth > 0 {
+				return strings.Join(strings.Fields(b.String()), " ")
+			}
+
+		case html.TextToken:
+			if depth > 0 {
+				b.Write(z.Text())
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenBlog(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	blog := DefaultBlogConfig
+	blog.PageSize = 2
+	cfg.Blog = &blog
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	for i, date := range []string{"2023-05-01", "2024-01-02", "2024-03-04"} {
+		_, err := h.Scan(
+			fmt.Sprintf("/posts/%d.md.html", i),
+			[]byte(fmt.Sprintf(`<!---
+date: %s
+tags: Go, Static Sites
+--->
+# Post %d
+
+First *paragraph* %d.
+
+Second paragraph.
+`, date, i, i)),
+		)
+		require.NoError(t, err)
+	}
+	// Pages outside of the posts dir, or without a date, are not posts.
+	_, err = h.Scan("/about.md.html", []byte("<!---\ndate: 2024-01-01\n--->\n# About"))
+	require.NoError(t, err)
+	p, err := h.Scan("/posts/draft.md.html", []byte(`<!---
+summary: Not yet.
+--->
+# Draft`))
+	require.NoError(t, err)
+	require.Equal(t, "Not yet.", p.Summary)
+
+	pages, err := h.GenBlog()
+	require.NoError(t, err)
+
+	var paths []string
+	for _, p := range pages {
+		paths = append(paths, p.RelPath)
+	}
+	require.Equal(t, []string{
+		"/blog/index.html",
+		"/blog/page/2.html",
+		"/blog/tags/go/index.html",
+		"/blog/tags/go/page/2.html",
+		"/blog/tags/static-sites/index.html",
+		"/blog/tags/static-sites/page/2.html",
+		"/blog/2024/index.html",
+		"/blog/2023/index.html",
+	}, paths)
+
+	html := string(pages[0].Html)
+	require.Contains(t, html, `<a href="/posts/2.md.html">Post 2</a>`)
+	require.Contains(t, html, `<time datetime="2024-01-02">Jan 2, 2024</time>`)
+	require.Contains(t, html, `<a class="blog_tag" href="/blog/tags/static-sites/index.html">Static Sites</a>`)
+	require.Contains(t, html, `<p class="blog_summary">First paragraph 1.</p>`)
+	require.Contains(t, html, `<a class="next" href="/blog/page/2.html">`)
+	require.NotContains(t, html, "Post 0")
+	require.NotContains(t, html, "About")
+	require.NotContains(t, html, "Draft")
+
+	html = string(pages[1].Html)
+	require.Contains(t, html, "Post 0")
+	require.Contains(t, html, `<a class="prev" href="/blog/index.html">`)
+
+	html = string(pages[len(pages)-1].Html)
+	require.Contains(t, html, "Blog: 2023")
+	require.NotContains(t, html, `<nav class="blog_pages">`)
+}
+
+func TestGenBlogHostileTags(t *testing.T) {
+	require.Equal(t, "static-sites", TagSlug(" Static  Sites "))
+	require.Equal(t, "ab", TagSlug("../a/b"))
+	require.Equal(t, "", TagSlug(".."))
+	require.Equal(t, "", TagSlug("?#%"))
+
+	cfg := DefaultConfig("", ".html")
+	blog := DefaultBlogConfig
+	cfg.Blog = &blog
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	_, err = h.Scan("/posts/a.md.html", []byte(`<!---
+date: 2024-01-02
+tags: .., Go, go, ../a/b, ?#%, 100%
+--->
+# A`))
+	require.NoError(t, err)
+
+	pages, err := h.GenBlog()
+	require.NoError(t, err)
+
+	var paths []string
+	for _, p := range pages {
+		paths = append(paths, p.RelPath)
+	}
+	require.Equal(t, []string{
+		"/blog/index.html",
+		"/blog/tags/100/index.html",
+		"/blog/tags/ab/index.html",
+		"/blog/tags/go/index.html",
+		"/blog/2024/index.html",
+	}, paths)
+
+	html := string(pages[0].Html)
+	require.Equal(t, 1, strings.Count(html, `<article class="blog_post">`))
+	require.Equal(t, 1, strings.Count(html, `href="/blog/tags/go/index.html"`))
+	require.NotContains(t, html, `href="/blog/index.html"`)
+
+	html = string(pages[3].Html)
+	require.Equal(t, 1, strings.Count(html, `<article class="blog_post">`))
+}
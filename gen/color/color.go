@@ -1,5 +1,12 @@
 package color
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type Color string
 
 const (
@@ -11,6 +18,8 @@ const (
 	Pink      Color = "#FFD1DC"
 	LightGray Color = "#F0F0F0"
 	DarkGray  Color = "#A9A9A9"
+	Black     Color = "#000000"
+	White     Color = "#FFFFFF"
 
 	HighlighterUltraRed   Color = "#FF7792"
 	HighlighterFrenchLime Color = "#AEFF77"
@@ -19,6 +28,82 @@ const (
 	HighlighterMacCheese  Color = "#FFAE77"
 )
 
+var (
+	ErrInvalidColor     = errors.New("invalid hex color")
+	ErrUnknownColor     = errors.New("unknown palette color")
+	ErrInvalidColorName = errors.New("invalid color name")
+)
+
 func (c Color) Hex() string {
 	return string(c)
 }
+
+// Contrast returns black or white, whichever is more readable as the text
+// color on c.
+func (c Color) Contrast() Color {
+	ch, ok := c.channels()
+	if !ok {
+		return Black
+	}
+
+	if 0.299*float64(ch[0])+0.587*float64(ch[1])+0.114*float64(ch[2]) > 150 {
+		return Black
+	}
+	return White
+}
+
+// WithAlpha returns c as #RRGGBBAA with its alpha scaled by a, e.g., to tint
+// a background. Colors which are not hex colors are returned unchanged.
+func (c Color) WithAlpha(a uint8) Color {
+	ch, ok := c.channels()
+	if !ok {
+		return c
+	}
+
+	ch[3] = uint8(int(ch[3]) * int(a) / 0xFF)
+	return Color(fmt.Sprintf("#%02X%02X%02X%02X", ch[0], ch[1], ch[2], ch[3]))
+}
+
+// channels returns the red, green, blue and alpha channels of a #RGB,
+// #RRGGBB or #RRGGBBAA color, alpha is 0xFF unless given.
+func (c Color) channels() ([4]uint8, bool) {
+	ch := [4]uint8{3: 0xFF}
+	s := strings.TrimPrefix(string(c), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 && len(s) != 8 {
+		return ch, false
+	}
+
+	for i := 0; i < len(s)/2; i++ {
+		v, err := strconv.ParseUint(s[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return ch, false
+		}
+		ch[i] = uint8(v)
+	}
+	return ch, true
+}
+
+// Parse parses a #RGB, #RRGGBB or #RRGGBBAA hex color.
+func Parse(s string) (Color, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "#") {
+		return "", ErrInvalidColor
+	}
+
+	switch len(s) - 1 {
+	case 3, 6, 8:
+	default:
+		return "", ErrInvalidColor
+	}
+
+	for _, c := range s[1:] {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return "", ErrInvalidColor
+		}
+	}
+
+	return Color(strings.ToUpper(s)), nil
+}
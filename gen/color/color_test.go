@@ -0,0 +1,95 @@
+package color
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse("#ff7792")
+	require.NoError(t, err)
+	require.Equal(t, Color("#FF7792"), c)
+
+	c, err = Parse(" #abc ")
+	require.NoError(t, err)
+	require.Equal(t, Color("#ABC"), c)
+
+	_, err = Parse("#12345")
+	require.ErrorIs(t, err, ErrInvalidColor)
+	_, err = Parse("ff7792")
+	require.ErrorIs(t, err, ErrInvalidColor)
+	_, err = Parse("#gg7792")
+	require.ErrorIs(t, err, ErrInvalidColor)
+}
+
+func TestContrast(t *testing.T) {
+	require.Equal(t, Black, HighlighterLaserLemon.Contrast())
+	require.Equal(t, Black, Color("#FFF").Contrast())
+	require.Equal(t, White, DarkPalette.Background.Contrast())
+	require.Equal(t, White, DarkPalette.HighlighterBlue.Contrast())
+	require.Equal(t, White, Color("#00000080").Contrast())
+}
+
+func TestWithAlpha(t *testing.T) {
+	require.Equal(t, Color("#FF00001A"), Red.WithAlpha(0x1A))
+	require.Equal(t, Color("#AABBCC1A"), Color("#ABC").WithAlpha(0x1A))
+	require.Equal(t, Color("#AABBCC0D"), Color("#AABBCC80").WithAlpha(0x1A))
+	require.Equal(t, Color("red"), Color("red").WithAlpha(0x1A))
+}
+
+func TestPaletteVars(t *testing.T) {
+	require.Equal(t, "--palette-light-gray", VarName("LightGray"))
+	require.Equal(
+		t,
+		"border: var(--palette-light-gray, #F0F0F0);",
+		DefaultPalette.ReplaceIn("border: {{ .Palette.LightGray }};"),
+	)
+	require.Contains(t, DarkPalette.Vars(), "--palette-background: #1A1A1A;\n")
+	require.Contains(t, DarkPalette.Vars(), "--palette-highlighter-blue-contrast: #FFFFFF;\n")
+	require.NotContains(t, DarkPalette.Vars(), "--palette-background-contrast")
+	require.Equal(
+		t,
+		"var(--palette-highlighter-blue-contrast, #000000)",
+		DefaultPalette.ContrastVar("HighlighterBlue"),
+	)
+}
+
+func TestOverride(t *testing.T) {
+	p, err := DefaultPalette.Override(
+		map[string]string{
+			"light_gray":      "#eee",
+			"HighlighterBlue": "#112233",
+			"highlighterred":  "#445566",
+		},
+		map[string]string{
+			"brand": "#5c7cfa",
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, Color("#EEE"), p.LightGray)
+	require.Equal(t, Color("#112233"), p.HighlighterBlue)
+	require.Equal(t, Color("#445566"), p.HighlighterRed)
+	require.Equal(t, Color("#5C7CFA"), p.Colors()["brand"])
+	require.Contains(t, p.Vars(), "--palette-brand: #5C7CFA;\n")
+	// The base palette is left untouched.
+	require.Equal(t, LightGray, DefaultPalette.LightGray)
+	require.Nil(t, DefaultPalette.Extra)
+
+	_, err = DefaultPalette.Override(map[string]string{"purple": "#800080"}, nil)
+	require.ErrorIs(t, err, ErrUnknownColor)
+	_, err = DefaultPalette.Override(map[string]string{"red": "red"}, nil)
+	require.ErrorIs(t, err, ErrInvalidColor)
+	_, err = DefaultPalette.Override(nil, map[string]string{"Brand": "#000"})
+	require.ErrorIs(t, err, ErrInvalidColorName)
+	_, err = DefaultPalette.Override(nil, map[string]string{"light_gray": "#000"})
+	require.ErrorIs(t, err, ErrInvalidColorName)
+	for _, name := range []string{"name", "b", "c", "d", "e"} {
+		_, err = DefaultPalette.Override(nil, map[string]string{name: "#000"})
+		require.ErrorIs(t, err, ErrInvalidColorName)
+	}
+
+	require.NoError(t, p.Validate())
+	p.Red = "red"
+	require.ErrorIs(t, p.Validate(), ErrInvalidColor)
+}
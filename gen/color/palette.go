@@ -1,6 +1,30 @@
 package color
 
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// contrastSuffix is appended to the variable name of a color for the
+	// text color on it, e.g., "--palette-highlighter-red-contrast".
+	contrastSuffix = "-contrast"
+)
+
 var (
+	// reservedNames are the names of the highlighter colors in markdown,
+	// e.g., <mark b>...</mark>.
+	reservedNames = map[string]bool{
+		"name": true,
+		"b":    true,
+		"c":    true,
+		"d":    true,
+		"e":    true,
+	}
+
 	DefaultPalette = Palette{
 		Red:       Red,
 		Green:     Green,
@@ -11,12 +35,38 @@ var (
 		LightGray: LightGray,
 		DarkGray:  DarkGray,
 
+		Foreground: Black,
+		Background: White,
+
 		HighlighterRed:    HighlighterUltraRed,
 		HighlighterGreen:  HighlighterFrenchLime,
 		HighlighterBlue:   HighlighterMayaBlue,
 		HighlighterYellow: HighlighterLaserLemon,
 		HighlighterOrange: HighlighterMacCheese,
 	}
+
+	// DarkPalette is the counterpart of DefaultPalette for dark color
+	// schemes. LightGray and DarkGray keep their roles, i.e., subtle
+	// backgrounds and borders, and secondary text.
+	DarkPalette = Palette{
+		Red:       "#FF6B6B",
+		Green:     "#69DB7C",
+		Blue:      "#74C0FC",
+		Yellow:    "#FFE066",
+		Orange:    "#FFA94D",
+		Pink:      "#F783AC",
+		LightGray: "#2E2E2E",
+		DarkGray:  "#8C8C8C",
+
+		Foreground: "#E6E6E6",
+		Background: "#1A1A1A",
+
+		HighlighterRed:    "#A8324A",
+		HighlighterGreen:  "#3F7A1F",
+		HighlighterBlue:   "#1F5F8A",
+		HighlighterYellow: "#7A7A1F",
+		HighlighterOrange: "#8A4F1F",
+	}
 )
 
 type Palette struct {
@@ -29,9 +79,201 @@ type Palette struct {
 	LightGray Color
 	DarkGray  Color
 
+	// Foreground and Background are the text and page colors.
+	Foreground Color
+	Background Color
+
 	HighlighterRed    Color
 	HighlighterGreen  Color
 	HighlighterBlue   Color
 	HighlighterYellow Color
 	HighlighterOrange Color
+
+	// Extra are named colors in addition to the fields, e.g., for
+	// highlights. Names are lower case, see ValidName.
+	Extra map[string]Color
+}
+
+// Colors returns the colors of the palette by field name, e.g., "LightGray",
+// and the extra colors by their names.
+func (p Palette) Colors() map[string]Color {
+	cm := map[string]Color{}
+	for _, name := range p.fieldNames() {
+		cm[name] = p.field(name)
+	}
+	for name, c := range p.Extra {
+		cm[name] = c
+	}
+	return cm
+}
+
+func (p Palette) fieldNames() []string {
+	var names []string
+	types := reflect.TypeOf(p)
+	for i := 0; i < types.NumField(); i++ {
+		if types.Field(i).Type == reflect.TypeOf(Color("")) {
+			names = append(names, types.Field(i).Name)
+		}
+	}
+	return names
+}
+
+func (p Palette) field(name string) Color {
+	return reflect.ValueOf(p).FieldByName(name).Interface().(Color)
+}
+
+// fieldName looks up the field of a color name given in any case, with
+// optional underscores, e.g., "light_gray" for "LightGray".
+func (p Palette) fieldName(name string) string {
+	key := strings.ToLower(strings.ReplaceAll(name, "_", ""))
+	for _, f := range p.fieldNames() {
+		if strings.ToLower(f) == key {
+			return f
+		}
+	}
+	return ""
+}
+
+// ValidName checks if a name is usable for an extra color, i.e., lower case
+// letters, digits and underscores starting with a letter. Names of the
+// palette fields are reserved, as are the names of the highlighter colors in
+// markdown, see reservedNames.
+func (p Palette) ValidName(name string) bool {
+	if name == "" || p.fieldName(name) != "" || reservedNames[name] {
+		return false
+	}
+	for i, c := range name {
+		if !(c >= 'a' && c <= 'z' || i > 0 && (c >= '0' && c <= '9' || c == '_')) {
+			return false
+		}
+	}
+	return true
+}
+
+// Override returns a copy of the palette with the colors named in colors
+// replaced, see fieldName for the naming, and the extra colors added. All
+// colors are hex values, see Parse.
+func (p Palette) Override(
+	colors map[string]string,
+	extra map[string]string,
+) (Palette, error) {
+	res := p
+	v := reflect.ValueOf(&res).Elem()
+	for name, hex := range colors {
+		f := p.fieldName(name)
+		if f == "" {
+			return p, fmt.Errorf("%w: %s", ErrUnknownColor, name)
+		}
+		c, err := Parse(hex)
+		if err != nil {
+			return p, fmt.Errorf("%s: %w", name, err)
+		}
+		v.FieldByName(f).Set(reflect.ValueOf(c))
+	}
+
+	res.Extra = map[string]Color{}
+	for name, c := range p.Extra {
+		res.Extra[name] = c
+	}
+	for name, hex := range extra {
+		if !p.ValidName(name) {
+			return p, fmt.Errorf("%w: %s", ErrInvalidColorName, name)
+		}
+		c, err := Parse(hex)
+		if err != nil {
+			return p, fmt.Errorf("%s: %w", name, err)
+		}
+		res.Extra[name] = c
+	}
+
+	return res, nil
+}
+
+// Validate checks that all colors of the palette are hex values.
+func (p Palette) Validate() error {
+	for name, c := range p.Colors() {
+		if _, err := Parse(c.Hex()); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ReplaceIn replaces the {{ .Palette.X }} placeholders in s with the CSS
+// variables of the palette colors, see Vars. The colors of the palette are
+// the fallback if the variables are not defined.
+func (p Palette) ReplaceIn(s string) string {
+	var pairs []string
+	for name, c := range p.Colors() {
+		pairs = append(
+			pairs,
+			"{{ .Palette."+name+" }}",
+			fmt.Sprintf("var(%s, %s)", VarName(name), c.Hex()),
+		)
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// Vars returns the CSS variable declarations of the palette colors, e.g.,
+// "--palette-light-gray: #F0F0F0;", in field order followed by the extra
+// colors by name. The highlighter and extra colors, which are used as
+// backgrounds, also declare the text color on them, see ContrastVar.
+func (p Palette) Vars() string {
+	var b strings.Builder
+	for _, name := range p.fieldNames() {
+		fmt.Fprintf(&b, "%s: %s;\n", VarName(name), p.field(name).Hex())
+	}
+	for _, name := range p.fieldNames() {
+		if strings.HasPrefix(name, "Highlighter") {
+			p.contrastVar(&b, name, p.field(name))
+		}
+	}
+
+	var extra []string
+	for name := range p.Extra {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		fmt.Fprintf(&b, "%s: %s;\n", VarName(name), p.Extra[name].Hex())
+		p.contrastVar(&b, name, p.Extra[name])
+	}
+
+	return b.String()
+}
+
+func (p Palette) contrastVar(b *strings.Builder, name string, c Color) {
+	fmt.Fprintf(b, "%s%s: %s;\n", VarName(name), contrastSuffix, c.Contrast().Hex())
+}
+
+// Var returns the CSS value of a palette color by field or extra name, its
+// variable with the color of p as the fallback.
+func (p Palette) Var(name string) string {
+	return fmt.Sprintf("var(%s, %s)", VarName(name), p.Colors()[name].Hex())
+}
+
+// ContrastVar returns the CSS value of the text color on a highlighter or
+// extra color, see Contrast, by its variable with the color of p as the
+// fallback.
+func (p Palette) ContrastVar(name string) string {
+	return fmt.Sprintf(
+		"var(%s%s, %s)",
+		VarName(name),
+		contrastSuffix,
+		p.Colors()[name].Contrast().Hex(),
+	)
+}
+
+// VarName returns the CSS variable name of a palette color field, e.g.,
+// "--palette-light-gray" for "LightGray".
+func VarName(field string) string {
+	var b strings.Builder
+	b.WriteString("--palette")
+	for i, c := range field {
+		if unicode.IsUpper(c) || i == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(unicode.ToLower(c))
+	}
+	return b.String()
 }
@@ -0,0 +1,385 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	rssVersion = "2.0"
+	atomXmlns  = "http://www.w3.org/2005/Atom"
+)
+
+// feedItem is a page published in a feed.
+type feedItem struct {
+	RelPath string
+	Title   string
+	Summary string
+	// Content is the article of the page, see Page.Content, prepared by
+	// feedContent. It is used instead of Summary by feeds with full content.
+	Content []byte
+	Tags    []string
+	// Published is the date of the page and Updated its last modification,
+	// see PageMeta.LastMod, or the date if unset.
+	Published time.Time
+	Updated   time.Time
+}
+
+// Feed generates the RSS 2.0 and Atom feeds of dated pages, the latest ones
+// first.
+type Feed struct {
+	domain string
+	title  string
+	// limit is the max number of items, no limit if 0.
+	limit int
+	// full puts the content of items into the feed, instead of their
+	// summaries.
+	full  bool
+	items []*feedItem
+}
+
+func NewFeed(domain string, title string, limit int, full bool) *Feed {
+	return &Feed{
+		domain: normalizeDomain(domain),
+		title:  title,
+		limit:  limit,
+		full:   full,
+	}
+}
+
+// Add adds a page to the feed with its content, e.g., Page.Content. Pages
+// without a date are skipped.
+func (f *Feed) Add(p *PageMeta, content []byte) {
+	if p.Date.IsZero() {
+		return
+	}
+	updated := p.Date
+	if p.LastMod.After(updated) {
+		updated = p.LastMod
+	}
+	f.items = append(f.items, &feedItem{
+		RelPath:   p.RelPath,
+		Title:     p.Title,
+		Summary:   p.Summary,
+		Content:   feedContent(content, f.loc(p.RelPath)),
+		Tags:      p.Tags,
+		Published: p.Date,
+		Updated:   updated,
+	})
+}
+
+// Tags returns the tags of the items with distinct slugs, see uniqueTags,
+// sorted.
+func (f *Feed) Tags() []string {
+	var all []string
+	for _, item := range f.items {
+		all = append(all, item.Tags...)
+	}
+	tags := uniqueTags(all)
+	sort.Strings(tags)
+	return tags
+}
+
+// TagFeed returns the feed of the items with the tag, or a tag sharing its
+// slug, see TagSlug.
+func (f *Feed) TagFeed(tag string, title string, limit int) *Feed {
+	tf := NewFeed(f.domain, title, limit, f.full)
+	for _, item := range f.items {
+		for _, t := range item.Tags {
+			if TagSlug(t) == TagSlug(tag) {
+				tf.items = append(tf.items, item)
+				break
+			}
+		}
+	}
+	return tf
+}
+
+// feedContent prepares the content of a page for feed readers, which show
+// it out of the site. References are resolved against the URL of the page,
+// and scripts and styles are dropped.
+func feedContent(content []byte, loc string) []byte {
+	base, err := url.Parse(loc)
+	if content == nil || err != nil {
+		return content
+	}
+
+	var buf bytes.Buffer
+	skip := ""
+	z := html.NewTokenizer(bytes.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return buf.Bytes()
+		}
+
+		raw := z.Raw()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if skip != "" {
+				continue
+			}
+			if t.Data == "script" || t.Data == "style" {
+				if tt == html.StartTagToken {
+					skip = t.Data
+				}
+				continue
+			}
+			if resolveRefs(base, t.Attr) {
+				buf.WriteString(t.String())
+				continue
+			}
+
+		case html.EndTagToken:
+			if skip != "" {
+				if name, _ := z.TagName(); string(name) == skip {
+					skip = ""
+				}
+				continue
+			}
+
+		default:
+			if skip != "" {
+				continue
+			}
+		}
+		buf.Write(raw)
+	}
+}
+
+// resolveRefs resolves the references in the attributes of a tag against
+// base. It returns true if any attribute is changed.
+func resolveRefs(base *url.URL, attrs []html.Attribute) bool {
+	resolve := func(ref string) string {
+		u, err := url.Parse(strings.TrimSpace(ref))
+		if err != nil {
+			return ref
+		}
+		return base.ResolveReference(u).String()
+	}
+
+	changed := false
+	for i, a := range attrs {
+		v := a.Val
+		switch a.Key {
+		case "href", "src", "poster":
+			v = resolve(a.Val)
+		case "srcset":
+			// Candidates are a URL and an optional descriptor, e.g.,
+			// "a-480w.png 480w, a.png 1000w".
+			cs := strings.Split(a.Val, ",")
+			for j, c := range cs {
+				fields := strings.Fields(c)
+				if len(fields) > 0 {
+					fields[0] = resolve(fields[0])
+				}
+				cs[j] = strings.Join(fields, " ")
+			}
+			v = strings.Join(cs, ", ")
+		}
+		if v != a.Val {
+			attrs[i].Val = v
+			changed = true
+		}
+	}
+	return changed
+}
+
+// latest returns the items to publish, the latest published first.
+func (f *Feed) latest() []*feedItem {
+	items := append([]*feedItem{}, f.items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].Published.Equal(items[j].Published) {
+			return items[i].Published.After(items[j].Published)
+		}
+		return items[i].RelPath < items[j].RelPath
+	})
+	if f.limit > 0 && len(items) > f.limit {
+		items = items[:f.limit]
+	}
+	return items
+}
+
+// updated returns the last modification of the items, or the current time
+// if there are none.
+func updated(items []*feedItem) time.Time {
+	if len(items) == 0 {
+		return time.Now().UTC()
+	}
+	t := items[0].Updated
+	for _, item := range items[1:] {
+		if item.Updated.After(t) {
+			t = item.Updated
+		}
+	}
+	return t
+}
+
+func (f *Feed) loc(rel string) string {
+	loc, _ := url.JoinPath(f.domain, normalizeRelPath(rel))
+	return loc
+}
+
+func (f *Feed) content(item *feedItem) string {
+	if f.full && item.Content != nil {
+		return string(item.Content)
+	}
+	return item.Summary
+}
+
+type rss struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XmlnsAtom string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Self          atomLink   `xml:"atom:link"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Guid        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+// GenRss generates the RSS 2.0 feed published at relPath.
+func (f *Feed) GenRss(relPath string) ([]byte, error) {
+	items := f.latest()
+	c := rssChannel{
+		Title:       f.title,
+		Link:        f.loc("/"),
+		Description: f.title,
+		Self: atomLink{
+			Href: f.loc(relPath),
+			Rel:  "self",
+			Type: "application/rss+xml",
+		},
+	}
+	if len(items) > 0 {
+		c.LastBuildDate = updated(items).Format(time.RFC1123Z)
+	}
+	for _, item := range items {
+		loc := f.loc(item.RelPath)
+		c.Items = append(c.Items, &rssItem{
+			Title:       item.Title,
+			Link:        loc,
+			Guid:        loc,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Description: f.content(item),
+			Categories:  item.Tags,
+		})
+	}
+
+	return marshalFeed(&rss{
+		Version:   rssVersion,
+		XmlnsAtom: atomXmlns,
+		Channel:   c,
+	})
+}
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"feed"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Links   []atomLink   `xml:"link"`
+	Updated string       `xml:"updated"`
+	Author  atomAuthor   `xml:"author"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// GenAtom generates the Atom feed published at relPath.
+func (f *Feed) GenAtom(relPath string) ([]byte, error) {
+	items := f.latest()
+	a := &atomFeed{
+		Xmlns: atomXmlns,
+		Title: f.title,
+		ID:    f.loc(relPath),
+		Links: []atomLink{
+			{Href: f.loc(relPath), Rel: "self"},
+			{Href: f.loc("/")},
+		},
+		// Updated is required, an empty feed is updated when it is built.
+		Updated: updated(items).Format(time.RFC3339),
+		// The site is the author of pages without authors.
+		Author: atomAuthor{Name: f.title},
+	}
+
+	for _, item := range items {
+		loc := f.loc(item.RelPath)
+		e := &atomEntry{
+			Title:     item.Title,
+			ID:        loc,
+			Link:      atomLink{Href: loc},
+			Updated:   item.Updated.Format(time.RFC3339),
+			Published: item.Published.Format(time.RFC3339),
+		}
+		if item.Summary != "" {
+			e.Summary = &atomText{Type: "text", Body: item.Summary}
+		}
+		if f.full && item.Content != nil {
+			e.Content = &atomText{Type: "html", Body: string(item.Content)}
+		}
+		for _, t := range item.Tags {
+			e.Categories = append(e.Categories, atomCategory{Term: t})
+		}
+		a.Entries = append(a.Entries, e)
+	}
+
+	return marshalFeed(a)
+}
+
+func marshalFeed(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
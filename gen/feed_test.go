@@ -0,0 +1,143 @@
+package gen
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+func testFeed(full bool) *Feed {
+	f := NewFeed("unnote.xyz", "Notes", 2, full)
+	for i, tags := range [][]string{{"go"}, {"go", "web"}, {"web"}} {
+		f.Add(&PageMeta{
+			RelPath: fmt.Sprintf("/posts/%d.md.html", i),
+			Title:   fmt.Sprintf("Post %d", i),
+			Summary: fmt.Sprintf("Summary %d", i),
+			Tags:    tags,
+			Date:    time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC),
+		}, []byte(fmt.Sprintf("<p>Content %d</p>", i)))
+	}
+	f.Add(&PageMeta{RelPath: "/undated.md.html", Title: "Undated"}, nil)
+	return f
+}
+
+func TestFeedRss(t *testing.T) {
+	f := testFeed(false)
+	data, err := f.GenRss("/feed.xml")
+	require.NoError(t, err)
+	rss := string(data)
+
+	require.Contains(t, rss, `<rss version="2.0"`)
+	require.Contains(t, rss, `<atom:link href="https://unnote.xyz/feed.xml" rel="self" type="application/rss+xml"></atom:link>`)
+	require.Contains(t, rss, `<lastBuildDate>Wed, 03 Jan 2024 00:00:00 +0000</lastBuildDate>`)
+	require.Contains(t, rss, `<item><title>Post 2</title><link>https://unnote.xyz/posts/2.md.html</link>`)
+	require.Contains(t, rss, `<description>Summary 1</description><category>go</category><category>web</category>`)
+	// The item limit keeps the latest items.
+	require.NotContains(t, rss, "Post 0")
+	require.NotContains(t, rss, "Undated")
+
+	require.Equal(t, []string{"go", "web"}, f.Tags())
+	f.Add(&PageMeta{
+		RelPath: "/posts/3.md.html",
+		Title:   "Post 3",
+		Tags:    []string{"Go", ".."},
+		Date:    time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+	}, nil)
+	// Tags sharing a slug are one tag, tags without a slug are dropped.
+	require.Equal(t, []string{"go", "web"}, f.Tags())
+	data, err = f.TagFeed("go", "Notes: go", 5).GenRss("/tags/go/feed.xml")
+	require.NoError(t, err)
+	rss = string(data)
+	require.Contains(t, rss, "Post 0")
+	require.Contains(t, rss, "Post 1")
+	require.NotContains(t, rss, "Post 2")
+	require.Contains(t, rss, "Post 3")
+}
+
+func TestFeedAtom(t *testing.T) {
+	data, err := testFeed(true).GenAtom("/atom.xml")
+	require.NoError(t, err)
+	atom := string(data)
+
+	require.Contains(t, atom, `<feed xmlns="http://www.w3.org/2005/Atom"><title>Notes</title><id>https://unnote.xyz/atom.xml</id>`)
+	require.Contains(t, atom, `<updated>2024-01-03T00:00:00Z</updated>`)
+	require.Contains(t, atom, `<entry><title>Post 2</title><id>https://unnote.xyz/posts/2.md.html</id>`)
+	require.Contains(t, atom, `<updated>2024-01-03T00:00:00Z</updated><published>2024-01-03T00:00:00Z</published><summary type="text">Summary 2</summary><content type="html">&lt;p&gt;Content 2&lt;/p&gt;</content>`)
+	require.NotContains(t, atom, "Post 0")
+
+	// Entries are updated at their last modification, and the feed at the
+	// latest one, while they are ordered by date.
+	f := testFeed(false)
+	f.Add(&PageMeta{
+		RelPath: "/posts/old.md.html",
+		Title:   "Old",
+		Date:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		LastMod: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}, nil)
+	f.limit = 0
+	data, err = f.GenAtom("/atom.xml")
+	require.NoError(t, err)
+	atom = string(data)
+	require.Contains(t, atom, `<id>https://unnote.xyz/atom.xml</id><link href="https://unnote.xyz/atom.xml" rel="self"></link><link href="https://unnote.xyz"></link><updated>2024-02-01T00:00:00Z</updated>`)
+	require.Contains(t, atom, `<updated>2024-02-01T00:00:00Z</updated><published>2023-01-01T00:00:00Z</published>`)
+	require.Regexp(t, `Post 0.*<entry><title>Old</title>`, atom)
+	data, err = f.GenRss("/feed.xml")
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<lastBuildDate>Thu, 01 Feb 2024 00:00:00 +0000</lastBuildDate>`)
+	require.Contains(t, string(data), `<pubDate>Sun, 01 Jan 2023 00:00:00 +0000</pubDate>`)
+
+	// An empty feed is updated when it is built.
+	data, err = NewFeed("unnote.xyz", "Notes", 0, false).GenAtom("/atom.xml")
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "0001-01-01")
+	require.Contains(t, string(data), "<updated>"+time.Now().UTC().Format("2006-"))
+}
+
+func TestFeedContent(t *testing.T) {
+	h, err := NewHtml(DefaultConfig("unnote.xyz", ".html"))
+	require.NoError(t, err)
+
+	src := []byte(`<!---
+date: 2024-01-02
+right_pane: toc
+--->
+# A
+
+[[toc]]
+
+![a](../img/a.png) [c](c.md) [x](https://x.org/)
+`)
+	meta, err := h.Scan("/guide/page.md.html", src)
+	require.NoError(t, err)
+	page, err := h.Gen("/guide/page.md.html", src)
+	require.NoError(t, err)
+
+	f := NewFeed("unnote.xyz", "Notes", 0, true)
+	f.Add(meta, page.Content)
+	require.Len(t, f.items, 1)
+	content := string(f.items[0].Content)
+	require.Contains(t, content, `src="https://unnote.xyz/img/a.png"`)
+	require.Contains(t, content, `href="https://unnote.xyz/guide/c.md.html"`)
+	require.Contains(t, content, `href="https://x.org/"`)
+	for _, s := range []string{"<html", "<head", "<script", "<style", markdown.ToCPlaceholder} {
+		require.NotContains(t, content, s)
+	}
+
+	data, err := f.GenAtom("/atom.xml")
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "&lt;html")
+	require.NotContains(t, string(data), "&lt;script")
+
+	require.Equal(
+		t,
+		`<p><a href="https://unnote.xyz/b.html#x">b</a></p><img srcset="https://unnote.xyz/a/c-480w.png 480w, https://unnote.xyz/a/c.png 960w"/><p>d</p>`,
+		string(feedContent(
+			[]byte(`<p><a href="../b.html#x">b</a></p><script>alert("<p>")</script><img srcset="c-480w.png 480w, c.png 960w"/><style>p{}</style><p>d</p>`),
+			"https://unnote.xyz/a/page.html",
+		)),
+	)
+}
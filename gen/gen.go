@@ -3,8 +3,13 @@ package gen
 import (
 	"bufio"
 	"bytes"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
 
 	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/keyword"
 	"github.com/iamjinlei/proteus/gen/markdown"
 )
 
@@ -13,6 +18,73 @@ type Config struct {
 	InternalRefHtmlSuffix string
 	LazyImageLoading      bool
 	Palette               color.Palette
+	// DarkMode switches to DarkPalette when the system of the reader
+	// prefers a dark color scheme.
+	DarkMode    bool
+	DarkPalette color.Palette
+	// ThemeToggle adds a button switching between the light and dark
+	// palettes, which remembers the choice of the reader. It implies
+	// DarkMode.
+	ThemeToggle bool
+	// PrevNext enables previous/next page links at the bottom of the main
+	// content. Pages can override it with the prev_next page config.
+	PrevNext bool
+	// PageOrder is the reading order of pages used by previous/next links,
+	// given as paths relative to the site root. The site tree order is used
+	// if empty.
+	PageOrder []string
+	// ToC is the site wide table of contents config. Pages can override it
+	// with the toc_* page configs.
+	ToC ToCConfig
+	// LinkProviders maps names usable in <mark kind="name"> highlights to
+	// lookup URL templates, in addition to markdown.DefaultLinkProviders.
+	LinkProviders map[string]string
+	// KeywordTypes maps user defined keyword types to their highlight
+	// colors, given as palette color names or hex values.
+	KeywordTypes map[string]string
+	// GlossaryPath is the path of the generated glossary page relative to
+	// the site root. No glossary is generated if empty.
+	GlossaryPath string
+	// Math is the math rendering mode of pages that enable math with
+	// "math: true" page config. Pages can also pick a mode with
+	// "math: mathml" or "math: katex". Math is off by default.
+	Math markdown.MathMode
+	// Diagrams maps a diagram language, e.g., "dot", to a local command
+	// rendering it into SVG at build time. Diagrams without a command are
+	// rendered in the browser.
+	Diagrams map[string]string
+	// PlantUMLServer renders plantuml diagrams without a command as images
+	// served by the PlantUML server at the URL, which receives the diagram
+	// sources. Sources are left as they are if empty.
+	PlantUMLServer string
+	// DiagramCacheDir keeps the SVG output of diagram commands across
+	// builds if set.
+	DiagramCacheDir string
+	// MarkdownExtensions are the names of the enabled markdown parser
+	// extensions, markdown.DefaultExtensions if nil.
+	MarkdownExtensions []string
+	Image              ImageConfig
+	// Lightbox zooms figure images on click, pages can override it with
+	// "lightbox" page config.
+	Lightbox bool
+	// Fingerprint puts the content hash into the file names of assets, so
+	// that they can be cached for long, see AddAsset.
+	Fingerprint bool
+	// SharedStatic collects the CSS and JS of all pages into shared static
+	// files linked by every page, instead of inlining them, see Static.
+	SharedStatic bool
+	// StaticPath is the directory of the shared static files relative to
+	// the site root, DefaultStaticPath if empty.
+	StaticPath string
+	// Minify collapses whitespace, removes comments and minifies the
+	// embedded CSS and JS of generated pages.
+	Minify bool
+	// Theme replaces the default layout and adds the theme static files and
+	// default page config, see LoadTheme and Theme.Apply.
+	Theme *Theme
+	// Blog generates listing pages of the dated pages in Blog.Dir, see
+	// GenBlog. No listing page is generated if nil.
+	Blog *BlogConfig
 }
 
 func DefaultConfig(
@@ -24,72 +96,227 @@ func DefaultConfig(
 		InternalRefHtmlSuffix: internalRefHtmlSuffix,
 		LazyImageLoading:      true,
 		Palette:               color.DefaultPalette,
+		DarkPalette:           color.DarkPalette,
+		ToC:                   DefaultToCConfig,
+		Math:                  markdown.MathMathML,
+		Image:                 DefaultImageConfig,
 	}
 }
 
+// Html generates the pages of a site, all pages are scanned before any is
+// generated, see Scan and Gen. It is not safe for concurrent use.
 type Html struct {
-	cfg Config
-	mdp *markdown.Parser
-	mdr *markdown.Renderer
-	r   *renderer
+	cfg  Config
+	mdp  *markdown.Parser
+	mdr  *markdown.Renderer
+	r    *renderer
+	site *siteTree
+	glos *glossary
+	// images maps the path of an image to its info, see AddImage.
+	images map[string]*markdown.ImageInfo
+	// assets maps the path of an asset to its fingerprinted path.
+	assets map[string]string
+	static *staticBundle
 }
 
 func NewHtml(cfg Config) (*Html, error) {
-	r, err := newRenderer(defaultLayout)
+	layout := defaultLayout
+	if cfg.Theme != nil && cfg.Theme.layout != "" {
+		layout = cfg.Theme.layout
+	}
+	r, err := newRenderer(layout)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := cfg.Palette.Validate(); err != nil {
+		return nil, fmt.Errorf("palette: %w", err)
+	}
+	if cfg.DarkMode || cfg.ThemeToggle {
+		if err := cfg.DarkPalette.Validate(); err != nil {
+			return nil, fmt.Errorf("dark palette: %w", err)
+		}
+	}
+
+	if cfg.Math != "" && !markdown.ValidMathMode(cfg.Math) {
+		return nil, fmt.Errorf("invalid math mode %q", cfg.Math)
+	}
+
+	exts := cfg.MarkdownExtensions
+	if exts == nil {
+		exts = markdown.DefaultExtensions
+	}
+	mdp, err := markdown.NewParserWithExtensions(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	mdr := markdown.NewRenderer(
+		cfg.Palette,
+		cfg.InternalRefHtmlSuffix,
+		cfg.LazyImageLoading,
+	)
+	for name, tmpl := range cfg.LinkProviders {
+		if err := mdr.RegisterLinkProvider(name, tmpl); err != nil {
+			return nil, fmt.Errorf("link provider %s: %w", name, err)
+		}
+	}
+
+	for t, c := range cfg.KeywordTypes {
+		v, err := mdr.Color(c)
+		if err != nil {
+			return nil, fmt.Errorf("keyword type %s: %w", t, err)
+		}
+		if err := mdr.RegisterKeywordType(keyword.Type(t), v); err != nil {
+			return nil, fmt.Errorf("keyword type %s: %w", t, err)
+		}
+	}
+
+	for lang, cmd := range cfg.Diagrams {
+		if err := mdr.RegisterDiagramCommand(lang, cmd); err != nil {
+			return nil, fmt.Errorf("diagram %s: %w", lang, err)
+		}
+	}
+	mdr.SetDiagramCacheDir(cfg.DiagramCacheDir)
+	mdr.SetPlantUMLServer(cfg.PlantUMLServer)
+
+	site := newSiteTree()
+	if cfg.GlossaryPath != "" {
+		site.add(&PageMeta{
+			RelPath: cfg.GlossaryPath,
+			Title:   defaultGlossaryTitle,
+		})
+	}
+
 	return &Html{
-		cfg: cfg,
-		mdp: markdown.NewParser(),
-		mdr: markdown.NewRenderer(
-			cfg.Palette,
-			cfg.InternalRefHtmlSuffix,
-			cfg.LazyImageLoading,
-		),
-		r: r,
+		cfg:    cfg,
+		mdp:    mdp,
+		mdr:    mdr,
+		r:      r,
+		site:   site,
+		glos:   newGlossary(),
+		images: map[string]*markdown.ImageInfo{},
+		assets: map[string]string{},
+		static: newStaticBundle(cfg.SharedStatic, cfg.StaticPath, cfg.Fingerprint),
 	}, nil
 }
 
+// RegisterTag registers a custom tag handler to the markdown renderer, see
+// markdown.Renderer.RegisterTag.
+func (h *Html) RegisterTag(name string, block bool, fn markdown.TagHandler) {
+	h.mdr.RegisterTag(name, block, fn)
+}
+
 type Page struct {
 	Html         []byte
 	InternalRefs []string
+	// RawSize is the size of Html before minification, see Config.Minify.
+	RawSize int
+	// Content is the rendered markdown of the page, without the layout and
+	// the components around it, e.g., for feeds.
+	Content []byte
+}
+
+// Scan discovers a page without generating it. All pages of a site must be
+// scanned before any of them is generated, so that site wide components,
+// e.g., the site tree, see the complete set of pages.
+func (h *Html) Scan(relPath string, src []byte) (*PageMeta, error) {
+	pCfg, md, err := h.pageConfig(src)
+	if err != nil {
+		return nil, err
+	}
+
+	mdDoc, err := h.renderMarkdown(relPath, pCfg, md)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PageMeta{
+		RelPath:      relPath,
+		Title:        pageTitle(relPath, pCfg, mdDoc),
+		Weight:       pCfg.weight(),
+		InternalRefs: internalRefs(pCfg, mdDoc),
+		Date:         pCfg.date(),
+		Tags:         pCfg.tags(),
+		Summary:      pCfg.summary(),
+		LastMod:      pCfg.lastMod(),
+		ChangeFreq:   pCfg.changeFreq(),
+		Priority:     pCfg.priority(),
+		NoIndex:      pCfg.noIndex(),
+	}
+	if p.Summary == "" {
+		p.Summary = firstParagraphText(mdDoc.Html)
+	}
+	h.site.add(p)
+	h.glos.add(p, mdDoc.Keywords)
+
+	if h.cfg.SharedStatic {
+		h.static.collect(h.pageChunks(relPath, pCfg, mdDoc))
+	}
+
+	return p, nil
+}
+
+// Static returns the shared static files linked by generated pages, see
+// Config.SharedStatic. No more CSS or JS is added to the files once they
+// are returned, or once any page is generated.
+func (h *Html) Static() []*Asset {
+	h.freezeStatic()
+	return h.static.files
+}
+
+func (h *Html) freezeStatic() {
+	if !h.cfg.SharedStatic || h.static.frozen {
+		return
+	}
+
+	if h.cfg.GlossaryPath != "" {
+		h.glossaryTemplateData()
+	}
+	if h.cfg.Blog != nil {
+		h.listingTemplateData(h.cfg.Blog.Path, &HtmlComponent{})
+	}
+	h.static.freeze()
+}
+
+// GenGlossary generates the site wide glossary page at Config.GlossaryPath
+// from the keywords of all scanned pages.
+func (h *Html) GenGlossary() (*Page, error) {
+	h.freezeStatic()
+	return h.renderPage(h.glossaryTemplateData())
 }
 
 func (h *Html) Gen(relPath string, src []byte) (*Page, error) {
-	pCfg, md, err := extractPageConfig(src)
+	h.freezeStatic()
+
+	pCfg, md, err := h.pageConfig(src)
 	if err != nil {
 		return nil, err
 	}
 
-	mdDoc, err := h.mdr.Render(h.mdp.Parse(md))
+	mdDoc, err := h.renderMarkdown(relPath, pCfg, md)
 	if err != nil {
 		return nil, err
 	}
 
-	refs := mdDoc.InternalRefs
-	if pCfg.bannerRef() != "" {
-		refs = append(refs, pCfg.bannerRef())
+	page, err := h.renderPage(h.pageTemplateData(relPath, pCfg, mdDoc))
+	if err != nil {
+		return nil, err
 	}
+	page.InternalRefs = internalRefs(pCfg, mdDoc)
+	page.Content = []byte(strings.NewReplacer(
+		"<p>"+markdown.ToCPlaceholder+"</p>", "",
+		markdown.ToCPlaceholder, "",
+	).Replace(string(mdDoc.Html)))
+
+	return page, nil
+}
 
+// renderPage renders the layout with the page data, minified if enabled.
+func (h *Html) renderPage(d *TemplateData) (*Page, error) {
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
-	if err := h.r.render(
-		w,
-		newTemplateData(
-			h.cfg.Domain,
-			relPath,
-			pCfg.header(),
-			pCfg.nav(),
-			&HtmlComponent{
-				Html: mdDoc.Html,
-			},
-			renderComponent(pCfg.leftPane(), mdDoc, h.cfg.Palette),
-			renderComponent(pCfg.rightPane(), mdDoc, h.cfg.Palette),
-			pCfg.footer(),
-		),
-	); err != nil {
+	if err := h.r.render(w, d); err != nil {
 		return nil, err
 	}
 
@@ -97,23 +324,264 @@ func (h *Html) Gen(relPath string, src []byte) (*Page, error) {
 		return nil, err
 	}
 
-	return &Page{
-		Html:         b.Bytes(),
-		InternalRefs: refs,
-	}, nil
+	page := &Page{
+		Html:    b.Bytes(),
+		RawSize: b.Len(),
+	}
+	if h.cfg.Minify {
+		page.Html = minifyHtml(page.Html)
+	}
+
+	return page, nil
+}
+
+func (h *Html) pageTemplateData(
+	relPath string,
+	pCfg *pageConfig,
+	doc *markdown.Doc,
+) *TemplateData {
+	return h.link(newTemplateData(
+		h.cfg.Domain,
+		relPath,
+		h.cfg.Palette,
+		pCfg.header(h.refRewriter(relPath)),
+		pCfg.nav(),
+		h.renderMain(relPath, pCfg, doc),
+		h.renderComponent(pCfg.leftPane(), relPath, pCfg, doc),
+		h.renderComponent(pCfg.rightPane(), relPath, pCfg, doc),
+		h.footer(pCfg),
+	))
+}
+
+// pageChunks returns the CSS and JS chunks of a scanned page, which are
+// collected into the shared static files. The components are not rendered
+// until the page is generated, e.g., the site tree is not complete before
+// all pages are scanned, so they only contribute their fixed chunks.
+func (h *Html) pageChunks(
+	relPath string,
+	pCfg *pageConfig,
+	doc *markdown.Doc,
+) []*markdown.Include {
+	palette := h.cfg.Palette
+	chunks := []*markdown.Include{h.layoutChunk()}
+	chunks = append(chunks, doc.Includes...)
+
+	if strings.Contains(string(doc.Html), markdown.ToCPlaceholder) {
+		chunks = append(chunks, tocChunks(pCfg.tocConfig(h.cfg.ToC), palette)...)
+		chunks = append(chunks, inlineToCChunk(palette))
+	}
+	if pCfg.lightbox(h.cfg.Lightbox) &&
+		strings.Contains(string(doc.Html), `<figure class="figure"`) {
+		chunks = append(chunks, renderLightbox().chunks()...)
+	}
+	if pCfg.prevNext(h.cfg.PrevNext) {
+		chunks = append(chunks, pageNavChunk(palette))
+	}
+
+	for _, kind := range []string{pCfg.leftPane(), pCfg.rightPane()} {
+		switch kind {
+		case "toc":
+			chunks = append(chunks, tocChunks(pCfg.tocConfig(h.cfg.ToC), palette)...)
+		case "kws":
+			chunks = append(chunks, kwsChunk(palette))
+		case "sitetree":
+			chunks = append(chunks, siteTreeChunk(palette))
+		}
+	}
+
+	for _, c := range []*HtmlComponent{
+		pCfg.header(h.refRewriter(relPath)),
+		pCfg.nav(),
+		h.footer(pCfg),
+	} {
+		chunks = append(chunks, c.chunks()...)
+	}
+
+	return chunks
+}
+
+func (h *Html) glossaryTemplateData() *TemplateData {
+	return h.link(newTemplateData(
+		h.cfg.Domain,
+		h.cfg.GlossaryPath,
+		h.cfg.Palette,
+		&HtmlComponent{},
+		&HtmlComponent{},
+		renderGlossary(h.glos, h.cfg.Palette),
+		&HtmlComponent{},
+		&HtmlComponent{},
+		h.footer(h.defaultPageConfig()),
+	))
+}
+
+func (h *Html) footer(pCfg *pageConfig) *HtmlComponent {
+	c := pCfg.footer()
+	if h.cfg.ThemeToggle {
+		tgl := renderThemeToggle(h.cfg.Palette)
+		c.Html += tgl.Html
+		c.merge(tgl)
+	}
+	return c
+}
+
+// link sets the styles and scripts of the page, either inlined or linked
+// from the shared static files.
+func (h *Html) link(d *TemplateData) *TemplateData {
+	if h.cfg.ThemeToggle {
+		d.HeadJs = template.JS(defaultThemeInitJs)
+	}
+
+	h.static.link(d, h.layoutChunk())
+	if theme := h.cfg.Theme; theme != nil {
+		d.Stylesheets = append(d.Stylesheets, theme.stylesheets()...)
+		d.Scripts = append(d.Scripts, theme.scripts()...)
+	}
+
+	return d
+}
+
+// layoutChunk returns the CSS of the layout.
+func (h *Html) layoutChunk() *markdown.Include {
+	var dark *color.Palette
+	if h.cfg.DarkMode || h.cfg.ThemeToggle {
+		dark = &h.cfg.DarkPalette
+	}
+
+	css := paletteCss(h.cfg.Palette, dark)
+	// A theme layout comes with its own styles.
+	if theme := h.cfg.Theme; theme == nil || theme.layout == "" {
+		css += h.cfg.Palette.ReplaceIn(defaultLayoutCss)
+	}
+
+	return &markdown.Include{
+		Css: template.CSS(css),
+	}
+}
+
+// defaultPageConfig returns the config of pages without page config.
+func (h *Html) defaultPageConfig() *pageConfig {
+	pCfg, _, _ := h.pageConfig(nil)
+	return pCfg
+}
+
+// pageConfig extracts the page config from src, with the defaults of the
+// theme.
+func (h *Html) pageConfig(src []byte) (*pageConfig, []byte, error) {
+	pCfg, md, err := extractPageConfig(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if h.cfg.Theme != nil {
+		pCfg.setDefaults(h.cfg.Theme.cfg.Page)
+	}
+	return pCfg, md, nil
 }
 
-func renderComponent(
+func (h *Html) renderMarkdown(
+	relPath string,
+	pCfg *pageConfig,
+	md []byte,
+) (*markdown.Doc, error) {
+	h.mdr.SetImageResolver(h.imageResolver(relPath))
+	h.mdr.SetRefRewriter(h.refRewriter(relPath))
+
+	math, err := pCfg.math(h.cfg.Math)
+	if err != nil {
+		return nil, err
+	}
+	if math == "" {
+		return h.mdr.Render(h.mdp.Parse(md))
+	}
+	return h.mdr.RenderWithMath(h.mdp.ParseMath(md), math)
+}
+
+func (h *Html) renderMain(
+	relPath string,
+	pCfg *pageConfig,
+	doc *markdown.Doc,
+) *HtmlComponent {
+	c := &HtmlComponent{
+		Html: doc.Html,
+	}
+	for _, i := range doc.Includes {
+		c.add(i)
+	}
+
+	if strings.Contains(string(c.Html), markdown.ToCPlaceholder) {
+		toc := renderInlineToC(
+			doc.Headings,
+			pCfg.tocConfig(h.cfg.ToC),
+			h.cfg.Palette,
+		)
+		c.Html = template.HTML(strings.NewReplacer(
+			"<p>"+markdown.ToCPlaceholder+"</p>", string(toc.Html),
+			markdown.ToCPlaceholder, string(toc.Html),
+		).Replace(string(c.Html)))
+		c.merge(toc)
+	}
+
+	if pCfg.lightbox(h.cfg.Lightbox) &&
+		strings.Contains(string(c.Html), `<figure class="figure"`) {
+		c.merge(renderLightbox())
+	}
+
+	if pCfg.prevNext(h.cfg.PrevNext) {
+		prev, next := h.site.neighbors(relPath, h.cfg.PageOrder)
+		nav := renderPageNav(prev, next, h.cfg.Palette)
+		c.Html += nav.Html
+		c.merge(nav)
+	}
+
+	return c
+}
+
+func (h *Html) renderComponent(
 	kind string,
+	relPath string,
+	pCfg *pageConfig,
 	doc *markdown.Doc,
-	palette color.Palette,
 ) *HtmlComponent {
 	switch kind {
 	case "toc":
-		return renderToC(doc.Headings, 3)
+		return renderToC(
+			doc.Headings,
+			pCfg.tocConfig(h.cfg.ToC),
+			h.cfg.Palette,
+		)
 	case "kws":
-		return renderKeywords(doc.Keywords, palette)
+		return renderKeywords(doc.Keywords, pCfg.kwsSort(), h.cfg.Palette)
+	case "sitetree":
+		return renderSiteTree(h.site, relPath, h.cfg.Palette)
 	}
 
 	return &HtmlComponent{}
 }
+
+func internalRefs(pCfg *pageConfig, doc *markdown.Doc) []string {
+	refs := doc.InternalRefs
+	if pCfg.bannerRef() != "" {
+		refs = append(refs, pCfg.bannerRef())
+	}
+	return refs
+}
+
+// pageTitle picks the page title from page config, then the first heading
+// and falls back to the file name.
+func pageTitle(relPath string, pCfg *pageConfig, doc *markdown.Doc) string {
+	if t := pCfg.title(); t != "" {
+		return t
+	}
+
+	for _, h := range doc.Headings {
+		if h.Name != "" {
+			return h.Name
+		}
+	}
+
+	name := filepath.Base(relPath)
+	for ext := filepath.Ext(name); ext != ""; ext = filepath.Ext(name) {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
@@ -0,0 +1,184 @@
+package gen
+
+import (
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/keyword"
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	defaultGlossaryTitle = "Glossary"
+
+	defaultGlossaryCss = `
+.glossary h2 {
+	border-bottom: 1px solid {{ .Palette.LightGray }};
+}
+.glossary dt {
+	display: inline-block;
+	border-radius: 4px;
+	padding: 2px 8px;
+	margin-top: 0.8em;
+}
+.glossary dd {
+	margin-left: 1em;
+}
+.glossary dd a {
+	margin-right: 1em;
+}
+`
+)
+
+type glossaryPage struct {
+	meta *PageMeta
+	kws  *markdown.Keywords
+}
+
+type glossaryOccurrence struct {
+	page   *PageMeta
+	target string
+}
+
+// glossaryColor holds the CSS values of the color of a keyword type and of
+// the text color on it.
+type glossaryColor struct {
+	bg string
+	fg string
+}
+
+type glossaryTerm struct {
+	value       string
+	occurrences []*glossaryOccurrence
+}
+
+// glossary collects keywords of all scanned pages into a site wide index.
+type glossary struct {
+	pages map[string]*glossaryPage
+}
+
+func newGlossary() *glossary {
+	return &glossary{
+		pages: map[string]*glossaryPage{},
+	}
+}
+
+func (g *glossary) add(meta *PageMeta, kws *markdown.Keywords) {
+	// A rescanned page replaces its previous keywords.
+	g.pages[meta.RelPath] = &glossaryPage{
+		meta: meta,
+		kws:  kws,
+	}
+}
+
+// terms returns the sorted terms of each keyword type, pages are visited in
+// path order so that occurrences are stable across builds.
+func (g *glossary) terms() (map[keyword.Type][]*glossaryTerm, map[keyword.Type]*glossaryColor) {
+	var paths []string
+	for p, _ := range g.pages {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	index := map[keyword.Type]map[string]*glossaryTerm{}
+	colors := map[keyword.Type]*glossaryColor{}
+	for _, p := range paths {
+		gp := g.pages[p]
+		for _, t := range gp.kws.Types() {
+			bg, fg := gp.kws.ColorCss(t)
+			colors[t] = &glossaryColor{bg: bg, fg: fg}
+			if index[t] == nil {
+				index[t] = map[string]*glossaryTerm{}
+			}
+
+			for _, kw := range gp.kws.Get(t) {
+				term := index[t][kw.Value]
+				if term == nil {
+					term = &glossaryTerm{
+						value: kw.Value,
+					}
+					index[t][kw.Value] = term
+				}
+				term.occurrences = append(term.occurrences, &glossaryOccurrence{
+					page:   gp.meta,
+					target: kw.Target,
+				})
+			}
+		}
+	}
+
+	res := map[keyword.Type][]*glossaryTerm{}
+	for t, m := range index {
+		for _, term := range m {
+			res[t] = append(res[t], term)
+		}
+		sort.Slice(res[t], func(i, j int) bool {
+			return res[t][i].value < res[t][j].value
+		})
+	}
+
+	return res, colors
+}
+
+func renderGlossary(g *glossary, palette color.Palette) *HtmlComponent {
+	terms, colors := g.terms()
+
+	var types []keyword.Type
+	for t, _ := range terms {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+
+	html := fmt.Sprintf(`<div class="glossary"><h1>%s</h1>`, defaultGlossaryTitle)
+	css := palette.ReplaceIn(defaultGlossaryCss)
+	for _, t := range types {
+		css += fmt.Sprintf(
+			".glossary dt.kw_%s {\n\tbackground-color: %s;\n\tcolor: %s;\n}\n",
+			t,
+			colors[t].bg,
+			colors[t].fg,
+		)
+
+		html += fmt.Sprintf(`<h2 id="kw_%s">%s</h2><dl>`, t, t)
+		for _, term := range terms[t] {
+			var links []string
+			cnt := 0
+			for i, o := range term.occurrences {
+				// Repeated occurrences on the same page are numbered.
+				cnt++
+				if i == 0 || term.occurrences[i-1].page != o.page {
+					cnt = 1
+				}
+				label := template.HTMLEscapeString(o.page.Title)
+				if cnt > 1 {
+					label = fmt.Sprintf("<sup>%d</sup>", cnt)
+				}
+				links = append(links, fmt.Sprintf(
+					`<a href="%s#%s">%s</a>`,
+					o.page.RelPath,
+					o.target,
+					label,
+				))
+			}
+
+			html += fmt.Sprintf(
+				`<dt class="kw_%s">%s</dt><dd>%s</dd>`,
+				t,
+				term.value,
+				strings.Join(links, ""),
+			)
+		}
+		html += "</dl>"
+	}
+	html += "</div>"
+
+	return &HtmlComponent{
+		Html: template.HTML(html),
+		Css:  template.CSS(css),
+	}
+}
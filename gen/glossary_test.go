@@ -0,0 +1,55 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/keyword"
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+func TestGlossary(t *testing.T) {
+	r := markdown.NewRenderer(color.DefaultPalette, ".html", false)
+	require.NoError(t, r.RegisterKeywordType("place", color.Green))
+	require.ErrorIs(t, r.RegisterKeywordType("Bad-Type", color.Green), markdown.ErrInvalidKeywordType)
+	require.ErrorIs(t, r.RegisterKeywordType("red", color.Green), markdown.ErrColorNameTaken)
+	require.ErrorIs(t, r.RegisterKeywordType("lightgray", color.Green), markdown.ErrColorNameTaken)
+	p := markdown.NewParser()
+
+	g := newGlossary()
+	for _, v := range []struct {
+		relPath string
+		src     string
+	}{
+		{"/b.md.html", "<mark place>Rome</mark> <mark name>Bob</mark>"},
+		{"/a.md.html", "<mark place>Rome</mark> <mark place>Oslo</mark> <mark place>Rome</mark>"},
+	} {
+		doc, err := r.Render(p.Parse([]byte(v.src)))
+		require.NoError(t, err)
+		g.add(&PageMeta{RelPath: v.relPath, Title: v.relPath}, doc.Keywords)
+	}
+
+	terms, colors := g.terms()
+	require.Equal(t, 2, len(terms))
+	// A registered color is not a palette color, which has a CSS variable.
+	require.Equal(t, &glossaryColor{bg: "#00FF00", fg: "#FFFFFF"}, colors["place"])
+	require.Equal(
+		t,
+		&glossaryColor{
+			bg: "var(--palette-highlighter-red, #FF7792)",
+			fg: "var(--palette-highlighter-red-contrast, #000000)",
+		},
+		colors[keyword.Name],
+	)
+	require.Equal(t, 1, len(terms[keyword.Name]))
+
+	places := terms["place"]
+	require.Equal(t, 2, len(places))
+	require.Equal(t, "Oslo", places[0].value)
+	require.Equal(t, "Rome", places[1].value)
+	require.Equal(t, 3, len(places[1].occurrences))
+	require.Equal(t, "/a.md.html", places[1].occurrences[0].page.RelPath)
+	require.Equal(t, "/b.md.html", places[1].occurrences[2].page.RelPath)
+}
@@ -0,0 +1,275 @@
+package gen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
+	"github.com/iamjinlei/proteus/gen/webp"
+)
+
+// ImageConfig controls the resized variants generated for images.
+type ImageConfig struct {
+	// Widths of the resized variants, only widths smaller than the original
+	// image are generated. No variants are generated if empty.
+	Widths []int
+	// Sizes is the sizes attribute of images with variants.
+	Sizes string
+	// Quality is the JPEG encoding quality of the variants.
+	Quality int
+	// WebP adds lossless WebP copies of PNG and JPEG images and their
+	// variants, offered with <picture> to browsers supporting them. They are
+	// only used if smaller than the image, which is usually the case for
+	// PNG images but not for JPEG photos.
+	WebP bool
+}
+
+var (
+	DefaultImageConfig = ImageConfig{
+		Widths:  []int{480, 960, 1600},
+		Sizes:   "(max-width: 960px) 100vw, 960px",
+		Quality: 85,
+	}
+)
+
+// Asset is a file generated by the build, RelPath is relative to the site
+// root.
+type Asset struct {
+	RelPath string
+	Data    []byte
+}
+
+// IsImage checks if a file is an image processed by AddImage.
+func IsImage(relPath string) bool {
+	switch strings.ToLower(filepath.Ext(relPath)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+		return true
+	}
+	return false
+}
+
+// AddImage records the size of an image referenced by pages and returns
+// its resized variants to be written next to it. Variants are registered
+// with AddAsset, the image itself is not. Pages generated afterwards
+// refer to the variants with srcset. Files that are not PNG, JPEG or GIF
+// images are ignored.
+func (h *Html) AddImage(relPath string, data []byte) ([]*Asset, error) {
+	if !IsImage(relPath) {
+		return nil, nil
+	}
+
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &markdown.ImageInfo{
+		Width:  cfg.Width,
+		Height: cfg.Height,
+		Sizes:  h.cfg.Image.Sizes,
+	}
+	h.images[relPath] = info
+
+	// Resizing would drop the animation of GIF images.
+	if format == "gif" {
+		return nil, nil
+	}
+
+	var widths []int
+	for _, w := range h.cfg.Image.Widths {
+		if w > 0 && w < cfg.Width {
+			widths = append(widths, w)
+		}
+	}
+	webP := h.cfg.Image.WebP &&
+		cfg.Width <= webp.MaxSize &&
+		cfg.Height <= webp.MaxSize
+	if len(widths) == 0 && !webP {
+		return nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []*Asset
+	if webP {
+		var buf bytes.Buffer
+		if err := webp.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		webP = buf.Len() < len(data)
+		if webP {
+			assets = append(assets, h.imageAsset(
+				markdown.ImageWebPPath(relPath),
+				buf.Bytes(),
+			))
+		}
+	}
+	info.WebP = webP
+
+	for _, w := range widths {
+		height := cfg.Height * w / cfg.Width
+		if height < 1 {
+			height = 1
+		}
+
+		var buf bytes.Buffer
+		resized := resizeImage(img, w, height)
+		switch format {
+		case "jpeg":
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{
+				Quality: h.cfg.Image.Quality,
+			})
+		default:
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		variant := markdown.ImageVariantPath(relPath, w)
+		info.Variants = append(info.Variants, w)
+		assets = append(assets, h.imageAsset(variant, buf.Bytes()))
+
+		if webP {
+			var buf bytes.Buffer
+			if err := webp.Encode(&buf, resized); err != nil {
+				return nil, err
+			}
+			assets = append(assets, h.imageAsset(
+				markdown.ImageWebPPath(variant),
+				buf.Bytes(),
+			))
+		}
+	}
+
+	return assets, nil
+}
+
+// imageAsset registers a generated image with AddAsset.
+func (h *Html) imageAsset(relPath string, data []byte) *Asset {
+	return &Asset{
+		RelPath: h.AddAsset(relPath, data),
+		Data:    data,
+	}
+}
+
+// imageResolver resolves image references of the page at relPath.
+func (h *Html) imageResolver(relPath string) markdown.ImageResolver {
+	return func(ref string) *markdown.ImageInfo {
+		return h.images[resolveRef(relPath, ref)]
+	}
+}
+
+// resizeImage scales an image down to w x h by averaging the source pixels
+// covered by every destination pixel.
+func resizeImage(src image.Image, w, h int) *image.NRGBA {
+	rgba := toRGBA(src)
+	b := rgba.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := y * sh / h
+		y1 := (y + 1) * sh / h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < w; x++ {
+			x0 := x * sw / w
+			x1 := (x + 1) * sw / w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i+0])
+					g += uint64(row[i+1])
+					bl += uint64(row[i+2])
+					a += uint64(row[i+3])
+				}
+				n += uint64(x1 - x0)
+			}
+
+			i := dst.PixOffset(x, y)
+			if a == 0 {
+				dst.Pix[i+3] = 0
+				continue
+			}
+			// Colors are alpha premultiplied, convert back to non
+			// premultiplied values.
+			dst.Pix[i+0] = uint8(r * 0xff / a)
+			dst.Pix[i+1] = uint8(g * 0xff / a)
+			dst.Pix[i+2] = uint8(bl * 0xff / a)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
+
+// toRGBA returns the alpha premultiplied pixels of an image, with the
+// bounds moved to the origin. The common decoded image types are converted
+// with direct pixel access, as going through image.Image for every pixel
+// is slow on large photos.
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+
+	switch s := src.(type) {
+	case *image.RGBA:
+		for y := 0; y < b.Dy(); y++ {
+			i := s.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(dst.Pix[y*dst.Stride:(y+1)*dst.Stride], s.Pix[i:i+b.Dx()*4])
+		}
+
+	case *image.NRGBA:
+		for y := 0; y < b.Dy(); y++ {
+			si := s.PixOffset(b.Min.X, b.Min.Y+y)
+			di := y * dst.Stride
+			for x := 0; x < b.Dx(); x++ {
+				a := uint32(s.Pix[si+3])
+				dst.Pix[di+0] = uint8(uint32(s.Pix[si+0]) * a / 0xff)
+				dst.Pix[di+1] = uint8(uint32(s.Pix[si+1]) * a / 0xff)
+				dst.Pix[di+2] = uint8(uint32(s.Pix[si+2]) * a / 0xff)
+				dst.Pix[di+3] = uint8(a)
+				si += 4
+				di += 4
+			}
+		}
+
+	case *image.YCbCr:
+		for y := 0; y < b.Dy(); y++ {
+			di := y * dst.Stride
+			for x := 0; x < b.Dx(); x++ {
+				yi := s.YOffset(b.Min.X+x, b.Min.Y+y)
+				ci := s.COffset(b.Min.X+x, b.Min.Y+y)
+				dst.Pix[di+0], dst.Pix[di+1], dst.Pix[di+2] = color.YCbCrToRGB(
+					s.Y[yi],
+					s.Cb[ci],
+					s.Cr[ci],
+				)
+				dst.Pix[di+3] = 0xff
+				di += 4
+			}
+		}
+
+	default:
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	}
+
+	return dst
+}
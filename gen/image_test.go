@@ -0,0 +1,141 @@
+package gen
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			src.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	cfg := DefaultConfig("", ".html")
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	assets, err := h.AddImage("/img/a.png", buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, assets, 2)
+	require.Equal(t, "/img/a-480w.png", assets[0].RelPath)
+	require.Equal(t, "/img/a-960w.png", assets[1].RelPath)
+
+	img, err := png.Decode(bytes.NewReader(assets[0].Data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 480, 240), img.Bounds())
+	require.Equal(
+		t,
+		color.NRGBA{R: 200, G: 100, B: 50, A: 255},
+		color.NRGBAModel.Convert(img.At(100, 100)),
+	)
+
+	page, err := h.Gen("/guide/page.md.html", []byte("![a](../img/a.png)"))
+	require.NoError(t, err)
+	require.Contains(
+		t,
+		string(page.Html),
+		`height="500" sizes="(max-width: 960px) 100vw, 960px" srcset="../img/a-480w.png 480w, ../img/a-960w.png 960w, ../img/a.png 1000w" width="1000"`,
+	)
+
+	assets, err = h.AddImage("/img/a.svg", []byte("<svg></svg>"))
+	require.NoError(t, err)
+	require.Empty(t, assets)
+}
+
+func TestAddImageWebP(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for y := 0; y < 500; y++ {
+		for x := 0; x < 1000; x++ {
+			src.Set(x, y, color.NRGBA{R: uint8(x / 100), G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, src))
+
+	cfg := DefaultConfig("", ".html")
+	cfg.Image.WebP = true
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	assets, err := h.AddImage("/img/a.png", buf.Bytes())
+	require.NoError(t, err)
+	var paths []string
+	for _, a := range assets {
+		paths = append(paths, a.RelPath)
+		if strings.HasSuffix(a.RelPath, ".webp") {
+			require.Equal(t, "RIFF", string(a.Data[:4]))
+			require.Equal(t, "WEBP", string(a.Data[8:12]))
+		}
+	}
+	require.Equal(t, []string{
+		"/img/a.png.webp",
+		"/img/a-480w.png",
+		"/img/a-480w.png.webp",
+		"/img/a-960w.png",
+		"/img/a-960w.png.webp",
+	}, paths)
+
+	page, err := h.Gen("/guide/page.md.html", []byte("![a](../img/a.png)"))
+	require.NoError(t, err)
+	require.Contains(
+		t,
+		string(page.Html),
+		`<picture><source type="image/webp" srcset="../img/a-480w.png.webp 480w, ../img/a-960w.png.webp 960w, ../img/a.png.webp 1000w" sizes="(max-width: 960px) 100vw, 960px"><img`,
+	)
+	require.Contains(t, string(page.Html), `</picture>`)
+}
+
+func TestResizeImage(t *testing.T) {
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420)
+	y, cb, cr := color.RGBToYCbCr(200, 100, 50)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = y
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i] = cb
+		ycbcr.Cr[i] = cr
+	}
+	nrgba := image.NewNRGBA(image.Rect(2, 2, 6, 6))
+	for y := 2; y < 6; y++ {
+		for x := 2; x < 6; x++ {
+			a := uint8(255)
+			if x < 4 {
+				a = 0
+			}
+			nrgba.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: a})
+		}
+	}
+	gray := image.NewGray(image.Rect(0, 0, 4, 4))
+	for i := range gray.Pix {
+		gray.Pix[i] = 80
+	}
+
+	for _, tc := range []struct {
+		src  image.Image
+		want color.NRGBA
+	}{
+		{ycbcr, color.NRGBA{R: 200, G: 100, B: 50, A: 255}},
+		{nrgba, color.NRGBA{R: 200, G: 100, B: 50, A: 127}},
+		{gray, color.NRGBA{R: 80, G: 80, B: 80, A: 255}},
+	} {
+		dst := resizeImage(tc.src, 1, 1)
+		require.Equal(t, image.Rect(0, 0, 1, 1), dst.Bounds())
+		got := dst.NRGBAAt(0, 0)
+		// YCbCr conversions are lossy.
+		require.InDelta(t, tc.want.R, got.R, 2)
+		require.InDelta(t, tc.want.G, got.G, 2)
+		require.InDelta(t, tc.want.B, got.B, 2)
+		require.Equal(t, tc.want.A, got.A)
+	}
+}
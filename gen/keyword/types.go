@@ -6,6 +6,18 @@ const (
 	Name Type = "name"
 )
 
+// ValidType checks if t can be used as a keyword type. A type is used as
+// the <mark> attribute name and in CSS class names, so it is limited to
+// lower case letters, digits and underscores.
 func ValidType(t string) bool {
-	return Type(t) == Name
+	if t == "" {
+		return false
+	}
+
+	for _, c := range t {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
 }
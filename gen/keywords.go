@@ -3,10 +3,10 @@ package gen
 import (
 	"fmt"
 	"html/template"
+	"sort"
 	"strings"
 
 	"github.com/iamjinlei/proteus/gen/color"
-	"github.com/iamjinlei/proteus/gen/keyword"
 	"github.com/iamjinlei/proteus/gen/markdown"
 )
 
@@ -16,6 +16,11 @@ const (
 	kwName kwType = "name"
 )
 
+const (
+	kwSortAlpha = "alpha"
+	kwSortFreq  = "freq"
+)
+
 const (
 	defaultKwsCss = `
 .kws {
@@ -28,7 +33,6 @@ const (
 }
 .kws .namebox {
 	display: inline-block;
-	background-color: {{ .NameBoxBgColor }};
 	border: 1px solid {{ .Palette.LightGray }};
 	border-radius:4px;
 	padding: 4px 8px;
@@ -36,49 +40,157 @@ const (
 }
 .kws a {
 	text-decoration: none;
-	color: #000000;
+	color: {{ .Palette.Foreground }};
+}
+.kws .kw_cnt {
+	margin-left: 0.4em;
+	padding: 0 0.4em;
+	border-radius: 0.8em;
+	font-size: 0.7em;
+	background-color: {{ .Palette.LightGray }};
+}
+.kws .kws_sort {
+	background-color: transparent;
+	border: 1px solid {{ .Palette.LightGray }};
+	border-radius: 4px;
+	margin: 4px;
+	font-size: 0.7em;
+	cursor: pointer;
 }
+`
+
+	defaultKwsJs = `
+document.addEventListener("click", function(e) {
+	var a = e.target.closest(".kws .namebox a");
+	if (a) {
+		e.preventDefault();
+		var targets = a.parentNode.getAttribute("data-targets").split(" ");
+		var idx = (parseInt(a.getAttribute("data-idx") || "-1") + 1) % targets.length;
+		a.setAttribute("data-idx", idx);
+		location.hash = targets[idx];
+		return;
+	}
+
+	var btn = e.target.closest(".kws_sort");
+	if (!btn) {
+		return;
+	}
+	var by = btn.getAttribute("data-sort");
+	var list = btn.parentNode.querySelector(".kws_list");
+	var boxes = Array.prototype.slice.call(list.children);
+	boxes.sort(function(a, b) {
+		if (by === "freq") {
+			var d = parseInt(b.getAttribute("data-count")) - parseInt(a.getAttribute("data-count"));
+			if (d !== 0) {
+				return d;
+			}
+		}
+		return a.getAttribute("data-value").localeCompare(b.getAttribute("data-value"));
+	});
+	boxes.forEach(function(b) { list.appendChild(b); });
+});
 `
 )
 
+type kwEntry struct {
+	kwType  string
+	value   string
+	targets []string
+}
+
+// keywordTypeCss returns the background color rules of keyword boxes, one
+// class per keyword type.
+func keywordTypeCss(kws *markdown.Keywords, selector string) string {
+	css := ""
+	for _, t := range kws.Types() {
+		bg, fg := kws.ColorCss(t)
+		css += fmt.Sprintf(
+			"%s.kw_%s {\n\tbackground-color: %s;\n\tcolor: %s;\n}\n",
+			selector,
+			t,
+			bg,
+			fg,
+		)
+	}
+	return css
+}
+
+// keywordEntries groups keyword occurrences by type and value. Entries of
+// a type are in document order unless sorted alphabetically or by frequency.
+func keywordEntries(kws *markdown.Keywords, sortBy string) []*kwEntry {
+	var entries []*kwEntry
+	for _, t := range kws.Types() {
+		index := map[string]*kwEntry{}
+		for _, kw := range kws.Get(t) {
+			e := index[kw.Value]
+			if e == nil {
+				e = &kwEntry{
+					kwType: string(t),
+					value:  kw.Value,
+				}
+				index[kw.Value] = e
+				entries = append(entries, e)
+			}
+			e.targets = append(e.targets, kw.Target)
+		}
+	}
+
+	switch sortBy {
+	case kwSortAlpha:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].value < entries[j].value
+		})
+	case kwSortFreq:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return len(entries[i].targets) > len(entries[j].targets)
+		})
+	}
+
+	return entries
+}
+
+func kwsChunk(palette color.Palette) *markdown.Include {
+	return &markdown.Include{
+		Css: template.CSS(palette.ReplaceIn(defaultKwsCss)),
+		Js:  template.JS(defaultKwsJs),
+	}
+}
+
 func renderKeywords(
 	kws *markdown.Keywords,
+	sortBy string,
 	palette color.Palette,
 ) *HtmlComponent {
-	list := kws.Get(keyword.Name)
+	entries := keywordEntries(kws, sortBy)
+	if len(entries) == 0 {
+		return &HtmlComponent{}
+	}
 
-	seen := map[string]bool{}
 	spans := ""
-	for _, kw := range list {
-		if seen[kw.Value] {
-			continue
-		}
-		seen[kw.Value] = true
-
+	for _, e := range entries {
 		spans += fmt.Sprintf(
-			`<span class="namebox"><a href="#%s">%s</a></span>`,
-			kw.Target,
-			kw.Value,
+			`<span class="namebox kw_%s" data-targets="%s" data-count="%d" data-value="%s"><a href="#%s">%s</a><span class="kw_cnt">%d</span></span>`,
+			e.kwType,
+			strings.Join(e.targets, " "),
+			len(e.targets),
+			template.HTMLEscapeString(e.value),
+			e.targets[0],
+			e.value,
+			len(e.targets),
 		)
 	}
-	css := strings.Replace(
-		defaultKwsCss,
-		"{{ .Palette.LightGray }}",
-		palette.LightGray.Hex(),
-		-1,
-	)
-	css = strings.Replace(
-		css,
-		"{{ .NameBoxBgColor }}",
-		kws.Color(keyword.Name).Hex(),
-		-1,
-	)
-
-	return &HtmlComponent{
+	c := &HtmlComponent{
 		Html: template.HTML(fmt.Sprintf(
-			`<div class="kws">%s</div>`,
+			`<div class="kws"><button class="kws_sort" data-sort="%s">A-Z</button><button class="kws_sort" data-sort="%s">#</button><div class="kws_list">%s</div></div>`,
+			kwSortAlpha,
+			kwSortFreq,
 			spans,
 		)),
-		Css: template.CSS(css),
 	}
+	c.add(kwsChunk(palette))
+	// The type colors differ between pages, keeping them apart lets the
+	// shared static files hold the rest.
+	c.include(template.CSS(keywordTypeCss(kws, ".kws .namebox")), "")
+
+	return c
 }
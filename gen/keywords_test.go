@@ -0,0 +1,34 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+func TestKeywordEntries(t *testing.T) {
+	doc, err := markdown.NewRenderer(color.DefaultPalette, ".html", false).Render(
+		markdown.NewParser().Parse([]byte(
+			"<mark name>Carol</mark> <mark name>Bob</mark> <mark name>Bob</mark> <mark name>Alice</mark>",
+		)),
+	)
+	require.NoError(t, err)
+
+	entries := keywordEntries(doc.Keywords, "")
+	require.Equal(t, 3, len(entries))
+	require.Equal(t, "Carol", entries[0].value)
+	require.Equal(t, "Bob", entries[1].value)
+	require.Equal(t, 2, len(entries[1].targets))
+	require.NotEqual(t, entries[1].targets[0], entries[1].targets[1])
+
+	entries = keywordEntries(doc.Keywords, kwSortAlpha)
+	require.Equal(t, "Alice", entries[0].value)
+	require.Equal(t, "Carol", entries[2].value)
+
+	entries = keywordEntries(doc.Keywords, kwSortFreq)
+	require.Equal(t, "Bob", entries[0].value)
+	require.Equal(t, "Carol", entries[1].value)
+}
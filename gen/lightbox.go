@@ -0,0 +1,87 @@
+package gen
+
+import (
+	"html/template"
+)
+
+const (
+	defaultLightboxCss = `
+.figure img {
+	cursor: zoom-in;
+}
+.lightbox {
+	position: fixed;
+	top: 0;
+	left: 0;
+	width: 100%;
+	height: 100%;
+	z-index: 100;
+	display: flex;
+	flex-direction: column;
+	align-items: center;
+	justify-content: center;
+	background-color: rgba(0, 0, 0, 0.85);
+	cursor: zoom-out;
+}
+.lightbox img {
+	max-width: 95%;
+	max-height: 90%;
+	object-fit: contain;
+}
+.lightbox .lightbox_caption {
+	margin-top: 0.6em;
+	color: #FFFFFF;
+	font-size: 0.9em;
+}
+`
+
+	defaultLightboxJs = `
+(function() {
+	var box = null;
+	function close() {
+		if (box) {
+			box.remove();
+			box = null;
+		}
+	}
+	document.querySelectorAll(".figure img").forEach(function(img) {
+		img.addEventListener("click", function() {
+			close();
+			box = document.createElement("div");
+			box.className = "lightbox";
+			var full = document.createElement("img");
+			// The src attribute refers to the original, not a resized variant.
+			full.src = img.getAttribute("src");
+			full.alt = img.alt;
+			box.appendChild(full);
+			var caption = img.parentElement.querySelector("figcaption");
+			if (caption) {
+				var c = document.createElement("div");
+				c.className = "lightbox_caption";
+				c.innerHTML = caption.innerHTML;
+				box.appendChild(c);
+			}
+			box.addEventListener("click", close);
+			document.body.appendChild(box);
+		});
+	});
+	document.addEventListener("keydown", function(e) {
+		if (e.key === "Escape") {
+			close();
+		}
+	});
+})();
+`
+)
+
+// renderLightbox returns the component that zooms figure images on click.
+// It binds to every figure of the page, so it is kept out of the shared
+// static files and only loaded by pages enabling it.
+func renderLightbox() *HtmlComponent {
+	c := &HtmlComponent{}
+	c.includeInline(
+		template.CSS(defaultLightboxCss),
+		template.JS(defaultLightboxJs),
+	)
+	return c
+}
@@ -0,0 +1,167 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+type admonitionKind struct {
+	icon  string
+	title string
+	// color is the name of the palette color of the kind.
+	color string
+}
+
+var (
+	admonitionKinds = map[string]*admonitionKind{
+		"note": &admonitionKind{
+			icon:  "ℹ️",
+			title: "Note",
+			color: "Blue",
+		},
+		"tip": &admonitionKind{
+			icon:  "\U0001F4A1",
+			title: "Tip",
+			color: "Green",
+		},
+		"warning": &admonitionKind{
+			icon:  "⚠️",
+			title: "Warning",
+			color: "Orange",
+		},
+		"danger": &admonitionKind{
+			icon:  "⛔",
+			title: "Danger",
+			color: "Red",
+		},
+	}
+
+	admonitionMarkerOpen  = []byte("[!")
+	admonitionMarkerClose = []byte("]")
+)
+
+func admonitionCss(palette color.Palette) string {
+	css := `
+.admonition {
+	margin: 1em 0;
+	padding: 0.2em 1em;
+	border-left: 4px solid;
+	border-radius: 4px;
+}
+.admonition_title {
+	font-weight: bold;
+}
+`
+	for _, name := range []string{"note", "tip", "warning", "danger"} {
+		c := admonitionKinds[name].color
+		// The tinted background adds alpha to the hex color, unless
+		// color-mix is supported to tint the palette variable.
+		css += fmt.Sprintf(
+			".admonition_%s {\n\tborder-color: {{ .Palette.%s }};\n\tbackground-color: %s;\n\tbackground-color: color-mix(in srgb, {{ .Palette.%s }} 10%%, transparent);\n}\n",
+			name,
+			c,
+			palette.Colors()[c].WithAlpha(0x1A).Hex(),
+			c,
+		)
+	}
+
+	return palette.ReplaceIn(css)
+}
+
+func admonitionOpen(w io.Writer, kind string, title string) {
+	k := admonitionKinds[kind]
+	if title == "" {
+		title = k.title
+	}
+	fmt.Fprintf(
+		w,
+		`<div class="admonition admonition_%s"><p class="admonition_title">%s %s</p>`,
+		kind,
+		k.icon,
+		html.EscapeString(title),
+	)
+}
+
+func admonitionClose(w io.Writer) {
+	fmt.Fprint(w, "</div>")
+}
+
+// parseAdmonitionMarker checks if a blockquote starts with a "[!KIND]" marker
+// line, e.g., "> [!NOTE] Optional title". If so, the marker line is removed
+// from the blockquote and the kind and title are returned.
+func parseAdmonitionMarker(n *ast.BlockQuote) (string, string, bool) {
+	if len(n.Children) == 0 {
+		return "", "", false
+	}
+	p, ok := n.Children[0].(*ast.Paragraph)
+	if !ok || len(p.Children) == 0 {
+		return "", "", false
+	}
+	t, ok := p.Children[0].(*ast.Text)
+	if !ok || !bytes.HasPrefix(t.Literal, admonitionMarkerOpen) {
+		return "", "", false
+	}
+
+	end := bytes.Index(t.Literal, admonitionMarkerClose)
+	if end == -1 {
+		return "", "", false
+	}
+	kind := strings.ToLower(string(t.Literal[len(admonitionMarkerOpen):end]))
+	if admonitionKinds[kind] == nil {
+		return "", "", false
+	}
+
+	line, rest := t.Literal[end+1:], []byte(nil)
+	if idx := bytes.IndexByte(line, '\n'); idx != -1 {
+		line, rest = line[:idx], line[idx+1:]
+	}
+	t.Literal = rest
+	if isEmptyParagraph(p) {
+		// The marker was the whole paragraph.
+		ast.RemoveFromTree(p)
+	}
+
+	return kind, string(bytes.TrimSpace(line)), true
+}
+
+func isEmptyParagraph(p *ast.Paragraph) bool {
+	for _, c := range p.Children {
+		t, ok := c.(*ast.Text)
+		if !ok || len(bytes.TrimSpace(t.Literal)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Renderer) renderBlockQuote(
+	w io.Writer,
+	n *ast.BlockQuote,
+	entering bool,
+) ast.WalkStatus {
+	if !entering {
+		if r.state.admonitions[n] {
+			admonitionClose(w)
+			return ast.GoToNext
+		}
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	kind, title, ok := parseAdmonitionMarker(n)
+	if !ok {
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	r.state.admonitions[n] = true
+	r.state.include("admonition", admonitionCss(r.palette), "")
+	admonitionOpen(w, kind, title)
+
+	return ast.GoToNext
+}
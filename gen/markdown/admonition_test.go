@@ -0,0 +1,61 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestAdmonition(t *testing.T) {
+	render := func(src string) string {
+		doc, err := NewRenderer(color.DefaultPalette, ".html", false).Render(NewParser().Parse([]byte(src)))
+		require.NoError(t, err)
+		return string(doc.Html)
+	}
+
+	html := render("> [!WARNING] Mind the gap\n> Body text\n")
+	require.True(t, strings.Contains(html, `<div class="admonition admonition_warning"><p class="admonition_title">⚠️ Mind the gap</p>`))
+	require.True(t, strings.Contains(html, "<p>Body text</p>"))
+	require.False(t, strings.Contains(html, "blockquote"))
+
+	html = render("> [!UNKNOWN]\n> Body text\n")
+	require.True(t, strings.Contains(html, "<blockquote>"))
+
+	html = render(`<ins type="tip">Some *tip*</ins>`)
+	require.True(t, strings.HasPrefix(html, `<div class="admonition admonition_tip"><p class="admonition_title">`))
+	require.True(t, strings.Contains(html, "Some <em>tip</em></div>"))
+}
+
+func TestAdmonitionMarkerOnly(t *testing.T) {
+	doc, err := NewRenderer(color.DefaultPalette, ".html", false).Render(
+		NewParser().Parse([]byte("> [!NOTE]\n>\n> Body text\n")),
+	)
+	require.NoError(t, err)
+	html := string(doc.Html)
+	require.Contains(t, html, `<p class="admonition_title">ℹ️ Note</p>`)
+	require.Contains(t, html, "<p>Body text</p>")
+	require.NotContains(t, html, "<p></p>")
+}
+
+func TestBookBibParagraph(t *testing.T) {
+	// Only admonitions and the table of contents are unwrapped, the
+	// bibliography keeps its paragraph.
+	doc, err := NewRenderer(color.DefaultPalette, ".html", false).Render(
+		NewParser().Parse([]byte(`<ins type="book_bib" title="T" author="A"/>`)),
+	)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(doc.Html), "<p>"))
+}
+
+func TestAdmonitionCss(t *testing.T) {
+	palette := color.DefaultPalette
+	palette.Blue = "#08F"
+	palette.Red = "#FF000080"
+	css := admonitionCss(palette)
+	require.Contains(t, css, "background-color: #0088FF1A;")
+	require.Contains(t, css, "background-color: #FF00000D;")
+	require.Contains(t, css, "background-color: #00FF001A;")
+}
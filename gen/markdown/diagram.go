@@ -0,0 +1,252 @@
+package markdown
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+var (
+	ErrInvalidDiagramLang    = errors.New("invalid diagram language")
+	ErrInvalidDiagramCommand = errors.New("invalid diagram command")
+
+	// DiagramLangs are the code block languages rendered as diagrams.
+	DiagramLangs = []string{"mermaid", "dot", "plantuml"}
+)
+
+const (
+	diagramCss = `
+.diagram {
+	margin: 1em 0;
+	text-align: center;
+	overflow-x: auto;
+}
+.diagram svg {
+	max-width: 100%;
+	height: auto;
+}
+.diagram pre {
+	display: inline-block;
+	text-align: left;
+}
+`
+
+	mermaidVersion = "10.9.1"
+
+	mermaidJs = `
+(function() {
+	import("https://cdn.jsdelivr.net/npm/mermaid@` + mermaidVersion + `/dist/mermaid.esm.min.mjs").then(function(m) {
+		m.default.initialize({startOnLoad: false});
+		m.default.run({querySelector: ".diagram_mermaid"});
+	});
+})();
+`
+
+	vizVersion = "3.11.0"
+
+	dotJs = `
+(function() {
+	var s = document.createElement("script");
+	s.src = "https://cdn.jsdelivr.net/npm/@viz-js/viz@` + vizVersion + `/lib/viz-standalone.js";
+	s.defer = true;
+	s.onload = function() {
+		Viz.instance().then(function(viz) {
+			document.querySelectorAll(".diagram_dot").forEach(function(e) {
+				try {
+					e.replaceChildren(viz.renderSVGElement(e.textContent));
+				} catch (err) {
+					// Leave the source in place if it does not render.
+				}
+			});
+		});
+	};
+	document.head.appendChild(s);
+})();
+`
+
+	// plantUMLAlphabet is the base64 variant used by PlantUML servers.
+	plantUMLAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-_"
+)
+
+func isDiagramLang(lang string) bool {
+	for _, l := range DiagramLangs {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterDiagramCommand renders code blocks of the diagram language with a
+// local command at build time, e.g., "dot -Tsvg" for dot diagrams. The
+// command reads the diagram source from stdin and writes SVG to stdout.
+// Without a command, mermaid and dot diagrams are rendered in the browser,
+// and plantuml diagrams by the server set with SetPlantUMLServer.
+func (r *Renderer) RegisterDiagramCommand(lang string, command string) error {
+	if !isDiagramLang(lang) {
+		return ErrInvalidDiagramLang
+	}
+	if len(strings.Fields(command)) == 0 {
+		return ErrInvalidDiagramCommand
+	}
+
+	r.diagramCmds[lang] = command
+	return nil
+}
+
+// SetPlantUMLServer renders plantuml diagrams without a command as images
+// served by a PlantUML server, e.g., "https://www.plantuml.com/plantuml/svg/".
+// The server receives the diagram sources, so there is none by default and
+// the sources are left as they are.
+func (r *Renderer) SetPlantUMLServer(url string) {
+	if url != "" && !strings.HasSuffix(url, "/") {
+		url += "/"
+	}
+	r.plantUMLServer = url
+}
+
+// SetDiagramCacheDir keeps the SVG output of diagram commands in dir, so
+// unchanged diagrams are not rendered again by later builds.
+func (r *Renderer) SetDiagramCacheDir(dir string) {
+	r.diagramCacheDir = dir
+}
+
+func codeBlockLang(n *ast.CodeBlock) string {
+	if f := strings.Fields(string(n.Info)); len(f) > 0 {
+		return strings.ToLower(f[0])
+	}
+	return ""
+}
+
+func (r *Renderer) renderDiagram(w io.Writer, lang string, src []byte) {
+	r.state.include("diagram", diagramCss, "")
+
+	if cmd := r.diagramCmds[lang]; cmd != "" {
+		svg, err := r.runDiagramCommand(cmd, src)
+		if err != nil {
+			r.state.err = fmt.Errorf("%s diagram: %w", lang, err)
+			return
+		}
+		fmt.Fprintf(w, `<div class="diagram">%s</div>`, svg)
+		return
+	}
+
+	switch lang {
+	case "mermaid":
+		r.state.includeInline("mermaid", mermaidJs)
+		fmt.Fprintf(
+			w,
+			`<div class="diagram"><pre class="diagram_mermaid">%s</pre></div>`,
+			html.EscapeString(string(src)),
+		)
+
+	case "dot":
+		r.state.includeInline("dot", dotJs)
+		fmt.Fprintf(
+			w,
+			`<div class="diagram"><pre class="diagram_dot">%s</pre></div>`,
+			html.EscapeString(string(src)),
+		)
+
+	case "plantuml":
+		if r.plantUMLServer == "" {
+			fmt.Fprintf(
+				w,
+				`<div class="diagram"><pre class="diagram_plantuml">%s</pre></div>`,
+				html.EscapeString(string(src)),
+			)
+			return
+		}
+
+		fmt.Fprintf(
+			w,
+			`<div class="diagram"><img src="%s%s" alt="diagram" loading="lazy"></div>`,
+			html.EscapeString(r.plantUMLServer),
+			plantUMLEncode(src),
+		)
+	}
+}
+
+func (r *Renderer) runDiagramCommand(command string, src []byte) ([]byte, error) {
+	sum := sha256.Sum256(append([]byte(command+"\n"), src...))
+	key := hex.EncodeToString(sum[:])
+
+	if svg, found := r.diagramCache[key]; found {
+		return svg, nil
+	}
+
+	cacheFile := ""
+	if r.diagramCacheDir != "" {
+		cacheFile = filepath.Join(r.diagramCacheDir, key+".svg")
+		if svg, err := os.ReadFile(cacheFile); err == nil {
+			r.diagramCache[key] = svg
+			return svg, nil
+		}
+	}
+
+	args := strings.Fields(command)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf(
+			"%w: %s",
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	svg := stdout.Bytes()
+	// Drop the XML declaration and doctype to inline the SVG.
+	if i := bytes.Index(svg, []byte("<svg")); i > 0 {
+		svg = svg[i:]
+	}
+
+	r.diagramCache[key] = svg
+	if cacheFile != "" {
+		if err := os.MkdirAll(r.diagramCacheDir, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(cacheFile, svg, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return svg, nil
+}
+
+// plantUMLEncode encodes a diagram source for a PlantUML server URL, i.e.,
+// the deflated source in PlantUML's base64 alphabet.
+func plantUMLEncode(src []byte) string {
+	var buf bytes.Buffer
+	// Writing to a bytes.Buffer never fails.
+	fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	fw.Write(src)
+	fw.Close()
+
+	data := buf.Bytes()
+	var b strings.Builder
+	for i := 0; i < len(data); i += 3 {
+		var c [3]byte
+		copy(c[:], data[i:])
+		b.WriteByte(plantUMLAlphabet[c[0]>>2])
+		b.WriteByte(plantUMLAlphabet[(c[0]&0x3)<<4|c[1]>>4])
+		b.WriteByte(plantUMLAlphabet[(c[1]&0xF)<<2|c[2]>>6])
+		b.WriteByte(plantUMLAlphabet[c[2]&0x3F])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package markdown
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRenderDiagramClient(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte(
+		"```mermaid\ngraph TD\n  A-->B\n```\n\n```dot\ndigraph { a -> b }\n```\n\n```go\nx := 1\n```\n",
+	)))
+	require.NoError(t, err)
+	require.Contains(
+		t,
+		string(doc.Html),
+		"<div class=\"diagram\"><pre class=\"diagram_mermaid\">graph TD\n  A--&gt;B\n</pre></div>",
+	)
+	require.Contains(
+		t,
+		string(doc.Html),
+		"<div class=\"diagram\"><pre class=\"diagram_dot\">digraph { a -&gt; b }\n</pre></div>",
+	)
+	require.Contains(t, string(doc.Html), "x := 1")
+	require.Contains(t, string(doc.Js), "mermaid")
+	require.Contains(t, string(doc.Js), "viz-standalone.js")
+
+	doc, err = r.Render(NewParser().Parse([]byte("```go\nx := 1\n```\n")))
+	require.NoError(t, err)
+	require.Empty(t, doc.Js)
+}
+
+func TestRenderDiagramCommand(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	require.ErrorIs(t, r.RegisterDiagramCommand("uml", "cat"), ErrInvalidDiagramLang)
+	require.ErrorIs(t, r.RegisterDiagramCommand("dot", " "), ErrInvalidDiagramCommand)
+	require.NoError(t, r.RegisterDiagramCommand("dot", "cat"))
+
+	dir := t.TempDir()
+	r.SetDiagramCacheDir(dir)
+
+	src := "```dot\n<?xml version=\"1.0\"?>\n<svg>a</svg>\n```\n"
+	doc, err := r.Render(NewParser().Parse([]byte(src)))
+	require.NoError(t, err)
+	require.Equal(t, "<div class=\"diagram\"><svg>a</svg>\n</div>", string(doc.Html))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.svg"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// Cached output is used instead of running the command again.
+	require.NoError(t, os.WriteFile(files[0], []byte("<svg>cached</svg>"), 0644))
+	r = NewRenderer(color.DefaultPalette, ".html", false)
+	require.NoError(t, r.RegisterDiagramCommand("dot", "cat"))
+	r.SetDiagramCacheDir(dir)
+	doc, err = r.Render(NewParser().Parse([]byte(src)))
+	require.NoError(t, err)
+	require.Equal(t, "<div class=\"diagram\"><svg>cached</svg></div>", string(doc.Html))
+
+	require.NoError(t, r.RegisterDiagramCommand("mermaid", "false"))
+	_, err = r.Render(NewParser().Parse([]byte("```mermaid\ngraph TD\n```\n")))
+	require.Error(t, err)
+}
+
+func TestRenderPlantUML(t *testing.T) {
+	src := "```plantuml\n@startuml\nBob -> Alice\n@enduml\n```\n"
+
+	// Sources are not sent to any server by default.
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte(src)))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<div class=\"diagram\"><pre class=\"diagram_plantuml\">@startuml\nBob -&gt; Alice\n@enduml\n</pre></div>",
+		string(doc.Html),
+	)
+	require.NotContains(t, string(doc.Html), "http")
+	require.NotContains(t, string(doc.Js), "http")
+
+	r.SetPlantUMLServer("https://uml.example.com/svg")
+	doc, err = r.Render(NewParser().Parse([]byte(src)))
+	require.NoError(t, err)
+	require.Contains(t, string(doc.Html), `<img src="https://uml.example.com/svg/`)
+}
+
+func TestPlantUMLEncode(t *testing.T) {
+	src := "@startuml\nBob -> Alice : hello\n@enduml\n"
+	enc := plantUMLEncode([]byte(src))
+
+	var data []byte
+	for i := 0; i < len(enc); i += 4 {
+		var v [4]byte
+		for j := range v {
+			v[j] = byte(strings.IndexByte(plantUMLAlphabet, enc[i+j]))
+		}
+		data = append(
+			data,
+			v[0]<<2|v[1]>>4,
+			v[1]<<4|v[2]>>2,
+			v[2]<<6|v[3],
+		)
+	}
+
+	dec, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	require.NoError(t, err)
+	require.Equal(t, src, string(dec))
+}
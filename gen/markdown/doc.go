@@ -10,11 +10,31 @@ type Doc struct {
 	InternalRefs []string
 	Headings     []*Heading
 	Keywords     *Keywords
+	// Css and Js are required by elements in Html. Includes holds the
+	// same CSS and JS as separate chunks, one per kind of element.
+	Css      template.CSS
+	Js       template.JS
+	Includes []*Include
 }
 
+// Include is a chunk of CSS and JS required by a kind of element.
+type Include struct {
+	Css template.CSS
+	Js  template.JS
+	// Inline is set if the JS does more than reacting to the elements it is
+	// required by, e.g., it loads KaTeX, or if it reacts to elements pages
+	// have without requiring it, e.g., the lightbox binding to figures. Such
+	// chunks are kept in the pages requiring them, and out of shared static
+	// files loaded by every page.
+	Inline bool
+}
+
+// Heading is a node of the document heading tree. Name is the plain text of
+// the heading and Html is its inline content with text level formatting.
 type Heading struct {
 	Level    int
 	ID       string
 	Name     string
+	Html     template.HTML
 	Children []*Heading
 }
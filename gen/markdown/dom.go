@@ -2,14 +2,46 @@ package markdown
 
 import (
 	"fmt"
+	"html"
 	"io"
 
 	"github.com/iamjinlei/proteus/gen/color"
 )
 
+const (
+	defaultBookBibCss = `
+.book_bib {
+	width: 100%;
+	margin-bottom: 100px;
+	border-bottom: 2px solid {{ .Palette.DarkGray }};
+	display: grid;
+	grid-template-columns: 1fr 2fr;
+}
+.book_bib img {
+	width: 100%;
+}
+.book_bib_info {
+	padding-left: 40px;
+}
+.book_bib_title {
+	font-size: 2em;
+	font-weight: bold;
+}
+.book_bib_author {
+	font-size: 1.2em;
+	margin-top: 5px;
+}
+`
+
+	highlightLinkCss = `
+.hl_link {
+	color: inherit;
+}
+`
+)
+
 func bookBibliography(
 	w io.Writer,
-	palette color.Palette,
 	title string,
 	coverImgRef string,
 	link string,
@@ -18,14 +50,13 @@ func bookBibliography(
 	fmt.Fprintf(
 		w,
 		`
-<div style="width:100%%;margin-bottom:100px;border-bottom: 2px solid %s;display:grid;grid-template-columns: 1fr 2fr;">
-	<span><a href="%s"><img src="%s" style="width:100%%"></a></span>
-	<span style="padding-left:40px;">
-		<div style="font-size:2em;font-weight: bold;">%s</div>
-		<div style="font-size:1.2em;margin-top:5px;">作者: %s</div>
+<div class="book_bib">
+	<span><a href="%s"><img src="%s"></a></span>
+	<span class="book_bib_info">
+		<div class="book_bib_title">%s</div>
+		<div class="book_bib_author">作者: %s</div>
 	</span>
 </div>`,
-		palette.DarkGray,
 		link,
 		coverImgRef,
 		title,
@@ -33,23 +64,39 @@ func bookBibliography(
 	)
 }
 
-func highlight(w io.Writer, id, content string, color color.Color) {
+func bookBibCss(palette color.Palette) string {
+	return palette.ReplaceIn(defaultBookBibCss)
+}
+
+// highlightCss returns the color rules of highlights of a kind, see
+// highlight. The text color contrasts with the highlight in both light and
+// dark color schemes.
+func highlightCss(kind string, bg, fg string) string {
+	return fmt.Sprintf(
+		"\n.hl_%s {\n\tbackground-color: %s;\n\tcolor: %s;\n}\n",
+		kind,
+		bg,
+		fg,
+	)
+}
+
+func highlight(w io.Writer, id, kind, content string) {
 	if id != "" {
-		id = fmt.Sprintf(`id="%s"`, id)
+		id = fmt.Sprintf(`id="%s" `, id)
 	}
 	fmt.Fprintf(
 		w,
-		`<span %s style="background-color:%s;">%s</span>`,
+		`<span %sclass="hl hl_%s">%s</span>`,
 		id,
-		color.Hex(),
+		kind,
 		content,
 	)
 }
 
 func link(content string, url string) string {
 	return fmt.Sprintf(
-		`<a href="%s" style="color:inherit;">%s</a>`,
-		url,
+		`<a href="%s" class="hl_link">%s</a>`,
+		html.EscapeString(url),
 		content,
 	)
 }
@@ -0,0 +1,116 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+
+	"github.com/gomarkdown/markdown/ast"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+const (
+	defaultFigureCss = `
+.figure {
+	margin: 1em 0;
+	text-align: center;
+}
+.figure figcaption {
+	margin-top: 0.4em;
+	font-size: 0.9em;
+	color: {{ .Palette.DarkGray }};
+}
+.figure_num {
+	font-weight: bold;
+	margin-right: 0.3em;
+}
+`
+)
+
+type figure struct {
+	id      string
+	num     int
+	caption string
+}
+
+func figureCss(palette color.Palette) string {
+	return palette.ReplaceIn(defaultFigureCss)
+}
+
+// isFigureParagraph checks if a paragraph only holds an image with a title,
+// optionally followed by an attribute list. Such an image is rendered as a
+// figure with the title as caption.
+func (r *Renderer) isFigureParagraph(p *ast.Paragraph) bool {
+	var img *ast.Image
+	for _, c := range p.Children {
+		switch v := c.(type) {
+		case *ast.Text:
+			lit := bytes.TrimSpace(v.Literal)
+			if len(lit) == 0 || img != nil && bytes.HasPrefix(lit, imageAttrsOpen) &&
+				bytes.HasSuffix(lit, imageAttrsClose) {
+				continue
+			}
+			return false
+		case *ast.Image:
+			if img != nil {
+				return false
+			}
+			img = v
+		default:
+			return false
+		}
+	}
+
+	// The title of a figure image is removed once it is rendered.
+	return img != nil && (len(img.Title) > 0 || r.state.figures[img] != nil)
+}
+
+func (r *Renderer) renderImage(
+	w io.Writer,
+	n *ast.Image,
+	entering bool,
+) ast.WalkStatus {
+	if !entering {
+		s := r.renderNodeDefault(w, n, entering)
+		if r.state.pictures[n] {
+			fmt.Fprint(w, "</picture>")
+		}
+		if f := r.state.figures[n]; f != nil {
+			fmt.Fprintf(
+				w,
+				`<figcaption><span class="figure_num">Figure %d.</span>%s</figcaption></figure>`+"\n",
+				f.num,
+				html.EscapeString(f.caption),
+			)
+		}
+		return s
+	}
+
+	if p, ok := n.Parent.(*ast.Paragraph); ok && r.isFigureParagraph(p) {
+		f := &figure{
+			num:     len(r.state.figures) + 1,
+			caption: string(n.Title),
+		}
+		f.id = r.state.slugger.unique(fmt.Sprintf("figure-%d", f.num))
+		r.state.figures[n] = f
+		r.state.include("figure", figureCss(r.palette), "")
+
+		// The title is shown as caption instead.
+		n.Title = nil
+		fmt.Fprintf(w, `<figure class="figure" id="%s">`, f.id)
+	}
+
+	ref := string(n.Destination)
+	if !isExternalLink(ref) {
+		r.state.internalRefs = append(r.state.internalRefs, ref)
+	}
+	if info := r.prepareImage(n); info != nil && info.WebP {
+		r.state.pictures[n] = true
+		r.pictureOpen(w, ref, info)
+	}
+	n.Destination = []byte(r.rewriteRef(ref))
+
+	return r.renderNodeDefault(w, n, entering)
+}
@@ -0,0 +1,29 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRenderFigure(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte(
+		"![a](a.png \"The <a>\")\n\n" +
+			"![b](b.png \"Two\"){width=50%}\n\n" +
+			"Inline ![c](c.png \"Title\") image.\n",
+	)))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<figure class=\"figure\" id=\"figure-1\"><img class=\"img\" src=\"a.png\" alt=\"a\" />"+
+			"<figcaption><span class=\"figure_num\">Figure 1.</span>The &lt;a&gt;</figcaption></figure>\n"+
+			"<figure class=\"figure\" id=\"figure-2\"><img class=\"img\" style=\"width:50%;\" src=\"b.png\" alt=\"b\" />"+
+			"<figcaption><span class=\"figure_num\">Figure 2.</span>Two</figcaption></figure>\n\n"+
+			"<p>Inline <img class=\"img\" src=\"c.png\" alt=\"c\" title=\"Title\" /> image.</p>\n",
+		string(doc.Html),
+	)
+	require.Contains(t, string(doc.Css), ".figure figcaption")
+}
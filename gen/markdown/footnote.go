@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+const (
+	footnoteReturnLink = "&#8617;&#xFE0E;"
+
+	defaultFootnoteCss = `
+.footnote-ref a {
+	text-decoration: none;
+	padding: 0 0.1em;
+}
+.footnotes {
+	font-size: 0.9em;
+}
+.footnotes hr {
+	border: none;
+	border-top: 1px solid {{ .Palette.LightGray }};
+}
+.footnote-return {
+	text-decoration: none;
+	margin-left: 0.3em;
+}
+.footnotes li:target {
+	background-color: {{ .Palette.HighlighterYellow }};
+}
+.footnote_preview {
+	position: absolute;
+	z-index: 10;
+	max-width: 30em;
+	padding: 0.4em 0.8em;
+	font-size: 0.9em;
+	background-color: {{ .Palette.Background }};
+	border: 1px solid {{ .Palette.LightGray }};
+	border-left: 3px solid {{ .Palette.DarkGray }};
+	border-radius: 4px;
+	box-shadow: 0 2px 6px rgba(0, 0, 0, 0.15);
+}
+`
+
+	footnoteJs = `
+(function() {
+	var preview = null;
+	function hide() {
+		if (preview) {
+			preview.remove();
+			preview = null;
+		}
+	}
+	document.querySelectorAll(".footnote-ref a").forEach(function(a) {
+		a.addEventListener("mouseenter", function() {
+			var note = document.getElementById(
+				decodeURIComponent(a.getAttribute("href").substring(1)));
+			if (!note) {
+				return;
+			}
+			hide();
+			preview = document.createElement("div");
+			preview.className = "footnote_preview";
+			preview.innerHTML = note.innerHTML;
+			preview.querySelectorAll(".footnote-return").forEach(function(e) {
+				e.remove();
+			});
+			var rect = a.getBoundingClientRect();
+			preview.style.left = (rect.left + window.scrollX) + "px";
+			preview.style.top = (rect.bottom + window.scrollY + 4) + "px";
+			document.body.appendChild(preview);
+		});
+		a.addEventListener("mouseleave", hide);
+	});
+})();
+`
+)
+
+func footnoteCss(palette color.Palette) string {
+	return palette.ReplaceIn(defaultFootnoteCss)
+}
+
+func (r *Renderer) includeFootnotes() {
+	r.state.include("footnote", footnoteCss(r.palette), footnoteJs)
+}
@@ -0,0 +1,169 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+const (
+	headingAnchorCss = `
+.heading_anchor {
+	visibility: hidden;
+	margin-left: 0.3em;
+	text-decoration: none;
+	color: {{ .Palette.DarkGray }};
+}
+h1:hover .heading_anchor,
+h2:hover .heading_anchor,
+h3:hover .heading_anchor,
+h4:hover .heading_anchor,
+h5:hover .heading_anchor,
+h6:hover .heading_anchor,
+.heading_anchor:focus {
+	visibility: visible;
+}
+.heading_anchor.copied::after {
+	content: " copied";
+	font-size: 0.5em;
+}
+`
+
+	headingAnchorJs = `
+document.addEventListener("click", function(e) {
+	var a = e.target.closest ? e.target.closest(".heading_anchor") : null;
+	if (!a || !navigator.clipboard) {
+		return;
+	}
+	var url = location.href.split("#")[0] + a.getAttribute("href");
+	navigator.clipboard.writeText(url).then(function() {
+		a.classList.add("copied");
+		setTimeout(function() { a.classList.remove("copied"); }, 1500);
+	});
+});
+`
+)
+
+// plainText concatenates the literal text of all descendants of n.
+func plainText(n ast.Node) string {
+	var b strings.Builder
+	ast.WalkFunc(n, func(n ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+
+		switch v := n.(type) {
+		case *ast.Text:
+			b.Write(v.Literal)
+		case *ast.Code:
+			b.Write(v.Literal)
+		case *ast.Math:
+			b.Write(v.Literal)
+		case *ast.Softbreak, *ast.Hardbreak, *ast.NonBlockingSpace:
+			b.WriteByte(' ')
+		}
+		return ast.GoToNext
+	})
+
+	return strings.TrimSpace(b.String())
+}
+
+// inlineHtml renders the inline content of n for use outside of the
+// document, e.g., in the table of contents. Only text level formatting is
+// kept. Links are unwrapped to avoid nested anchors, images are replaced by
+// their alt text and raw HTML is dropped.
+func inlineHtml(n ast.Node) template.HTML {
+	var b strings.Builder
+	ast.WalkFunc(n, func(c ast.Node, entering bool) ast.WalkStatus {
+		tag := ""
+		switch v := c.(type) {
+		case *ast.Text:
+			if entering {
+				b.WriteString(html.EscapeString(string(v.Literal)))
+			}
+		case *ast.Code:
+			if entering {
+				fmt.Fprintf(&b, "<code>%s</code>", html.EscapeString(string(v.Literal)))
+			}
+		case *ast.Math:
+			if entering {
+				b.WriteString(html.EscapeString(string(v.Literal)))
+			}
+		case *ast.Softbreak, *ast.Hardbreak, *ast.NonBlockingSpace:
+			if entering {
+				b.WriteByte(' ')
+			}
+		case *ast.Emph:
+			tag = "em"
+		case *ast.Strong:
+			tag = "strong"
+		case *ast.Del:
+			tag = "del"
+		case *ast.Subscript:
+			if entering {
+				fmt.Fprintf(&b, "<sub>%s</sub>", html.EscapeString(string(v.Literal)))
+			}
+		case *ast.Superscript:
+			if entering {
+				fmt.Fprintf(&b, "<sup>%s</sup>", html.EscapeString(string(v.Literal)))
+			}
+		}
+
+		if tag != "" {
+			if entering {
+				fmt.Fprintf(&b, "<%s>", tag)
+			} else {
+				fmt.Fprintf(&b, "</%s>", tag)
+			}
+		}
+		return ast.GoToNext
+	})
+
+	return template.HTML(strings.TrimSpace(b.String()))
+}
+
+// claimExplicitHeadingIDs de-duplicates explicit {#id} heading IDs and marks
+// them as taken before rendering, so that generated IDs of earlier headings
+// never collide with them.
+func claimExplicitHeadingIDs(root ast.Node, s *slugger) {
+	ast.WalkFunc(root, func(n ast.Node, entering bool) ast.WalkStatus {
+		if h, ok := n.(*ast.Heading); ok && entering && h.HeadingID != "" {
+			h.HeadingID = s.unique(h.HeadingID)
+		}
+		return ast.GoToNext
+	})
+}
+
+func (r *Renderer) renderHeading(
+	w io.Writer,
+	n *ast.Heading,
+	entering bool,
+) ast.WalkStatus {
+	if !entering {
+		fmt.Fprintf(
+			w,
+			`<a class="heading_anchor" href="#%s" aria-label="Link to this section">#</a>`,
+			n.HeadingID,
+		)
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	name := plainText(n)
+	if n.HeadingID == "" {
+		n.HeadingID = r.state.slugger.unique(slugify(name))
+	}
+	r.state.include(
+		"heading_anchor",
+		r.palette.ReplaceIn(headingAnchorCss),
+		headingAnchorJs,
+	)
+
+	h := r.state.ht.add(n.Level, n.HeadingID, name)
+	h.Html = inlineHtml(n)
+
+	return r.renderNodeDefault(w, n, entering)
+}
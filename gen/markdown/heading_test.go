@@ -0,0 +1,28 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRenderHeadings(t *testing.T) {
+	src := "# Using `gen.Html`\n\n## **Bold** [title](http://a.b) a < b\n\n## Intro\n\n## Intro {#custom}\n\n## Intro\n"
+	doc, err := NewRenderer(color.DefaultPalette, ".html", false).Render(NewParser().Parse([]byte(src)))
+	require.NoError(t, err)
+
+	require.Equal(t, 1, len(doc.Headings))
+	h := doc.Headings[0]
+	require.Equal(t, "using-genhtml", h.ID)
+	require.Equal(t, "Using gen.Html", h.Name)
+	require.Equal(t, "Using <code>gen.Html</code>", string(h.Html))
+
+	require.Equal(t, 4, len(h.Children))
+	require.Equal(t, "Bold title a < b", h.Children[0].Name)
+	require.Equal(t, "<strong>Bold</strong> title a &lt; b", string(h.Children[0].Html))
+	require.Equal(t, "intro", h.Children[1].ID)
+	require.Equal(t, "custom", h.Children[2].ID)
+	require.Equal(t, "intro-1", h.Children[3].ID)
+}
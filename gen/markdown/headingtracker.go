@@ -8,7 +8,7 @@ func newHeadingTracker() *headingTracker {
 	return &headingTracker{}
 }
 
-func (t *headingTracker) add(level int, id, name string) {
+func (t *headingTracker) add(level int, id, name string) *Heading {
 	h := &Heading{
 		Level: level,
 		ID:    id,
@@ -27,7 +27,7 @@ func (t *headingTracker) add(level int, id, name string) {
 		}
 		// The first heading inserted.
 		t.queue = append(t.queue, []*Heading{h})
-		return
+		return h
 	}
 
 	currList := t.queue[len(t.queue)-1]
@@ -58,9 +58,15 @@ func (t *headingTracker) add(level int, id, name string) {
 		currList := t.queue[len(t.queue)-1]
 		t.queue[len(t.queue)-1] = append(currList, h)
 	}
+
+	return h
 }
 
 func (t *headingTracker) getHeadings() []*Heading {
+	if len(t.queue) == 0 {
+		return nil
+	}
+
 	// Trim empty headings if the top heading level is > 1
 	idx := 0
 	for idx < len(t.queue) {
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"sort"
 	"strings"
 
@@ -15,12 +17,13 @@ var (
 )
 
 var (
-	htmlClosingTagPrefix = []byte("</")
-	htmlClosingTagMark   = []byte("</mark>")
-	htmlClosingTagIns    = []byte("</ins>")
-	htmlClosingTagDiv    = []byte("</div>")
-	htmlClosingTagSpan   = []byte("</span>")
-	htmlClosingTagASpan  = []byte("</a></span>")
+	htmlClosingTagPrefix     = []byte("</")
+	htmlSelfClosingTagSuffix = []byte("/>")
+	htmlClosingTagMark       = []byte("</mark>")
+	htmlClosingTagIns        = []byte("</ins>")
+	htmlClosingTagDiv        = []byte("</div>")
+	htmlClosingTagSpan       = []byte("</span>")
+	htmlClosingTagASpan      = []byte("</a></span>")
 )
 
 func parseTag(data []byte) (*html.Node, error) {
@@ -133,6 +136,82 @@ func encodeStyle(style map[string]string) string {
 	return strings.Join(arr, ";")
 }
 
+// htmlRefAttrs are the attributes of raw HTML tags referencing assets.
+var htmlRefAttrs = map[string][]string{
+	"a":      {"href"},
+	"audio":  {"src"},
+	"img":    {"src"},
+	"source": {"src"},
+	"video":  {"src", "poster"},
+}
+
+// rewriteHTMLRefs rewrites the references of the tags in raw HTML, see
+// rewriteTagRefs. Other parts of the HTML are kept as is.
+func (r *Renderer) rewriteHTMLRefs(data []byte) []byte {
+	var buf bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				// Keep the HTML that the tokenizer fails on.
+				return data
+			}
+			return buf.Bytes()
+		}
+
+		raw := z.Raw()
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			t := z.Token()
+			if r.rewriteTagRefs(t.Data, t.Attr) {
+				s := t.String()
+				if tt == html.SelfClosingTagToken {
+					s = strings.TrimSuffix(s, "/>") + " />"
+				}
+				buf.WriteString(s)
+				continue
+			}
+		}
+		buf.Write(raw)
+	}
+}
+
+// rewriteTagRefs rewrites the references in the attributes of a tag, see
+// htmlRefAttrs, to the URLs of the assets they refer to, e.g., content
+// hashed names. Unlike markdown links, raw HTML is written by hand, so
+// other references are kept as is: links to pages are not given the page
+// suffix and are not reported as internal references. It returns true if
+// any attribute is changed.
+func (r *Renderer) rewriteTagRefs(tag string, attrs []html.Attribute) bool {
+	rewritten := false
+	for _, key := range htmlRefAttrs[tag] {
+		for i, a := range attrs {
+			if a.Key != key || !isLocalRef(a.Val) {
+				continue
+			}
+
+			ref, frag, hasFrag := strings.Cut(a.Val, "#")
+			u := r.rewriteRef(ref)
+			if u == ref {
+				continue
+			}
+			if hasFrag {
+				u += "#" + frag
+			}
+			attrs[i].Val = u
+			rewritten = true
+		}
+	}
+	return rewritten
+}
+
+// isLocalRef checks if a reference is a path on the site, which excludes
+// URLs with a scheme or a host, and fragments of the page itself.
+func isLocalRef(ref string) bool {
+	u, err := url.Parse(ref)
+	return err == nil && u.Scheme == "" && u.Host == "" && u.Path != ""
+}
+
 func isExternalLink(ref string) bool {
 	return strings.HasPrefix(ref, "http://") ||
 		strings.HasPrefix(ref, "https://")
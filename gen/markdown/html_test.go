@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRawHtmlRefs(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	// Only assets are rewritten, e.g., to content hashed names.
+	assets := map[string]bool{"p.png": true, "v.mp4": true, "doc.pdf": true}
+	r.SetRefRewriter(func(ref string) string {
+		if assets[ref] {
+			return "/static/" + ref
+		}
+		return ref
+	})
+
+	doc, err := r.Render(NewParser().Parse([]byte(`Inline <a href="about.html">about</a> <a href="/">home</a> <a href="b.md#sec" class="x">B</a> <a href="doc.pdf#page=2">doc</a> <a href="other.pdf">other</a> <a href="#top">top</a> <a href="mailto:a@b.c">mail</a>.
+
+<video poster="p.png" controls>
+  <source src="v.mp4" type="video/mp4">
+  <img src="https://example.com/a.png">
+</video>
+`)))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<p>Inline <a href=\"about.html\">about</a> <a href=\"/\">home</a> <a href=\"b.md#sec\" class=\"x\">B</a> <a href=\"/static/doc.pdf#page=2\">doc</a> <a href=\"other.pdf\">other</a> <a href=\"#top\">top</a> <a href=\"mailto:a@b.c\">mail</a>.</p>\n\n"+
+			"<video poster=\"/static/p.png\" controls=\"\">\n"+
+			"  <source src=\"/static/v.mp4\" type=\"video/mp4\">\n"+
+			"  <img src=\"https://example.com/a.png\">\n"+
+			"</video>\n",
+		string(doc.Html),
+	)
+	// Raw HTML links are not crawled.
+	require.Empty(t, doc.InternalRefs)
+}
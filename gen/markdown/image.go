@@ -0,0 +1,219 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+const (
+	defaultImageSizes = "100vw"
+
+	// The height is scaled with the width, as the intrinsic size of images
+	// is set when known.
+	imageCss = `
+.img {
+	width: 100%;
+	height: auto;
+}
+`
+)
+
+var (
+	imageAttrsOpen  = []byte("{")
+	imageAttrsClose = []byte("}")
+)
+
+// ImageInfo describes an image file referenced by a document. Width and
+// Height are the intrinsic size of the image and Variants are the widths of
+// its resized copies, see ImageVariantPath.
+type ImageInfo struct {
+	Width    int
+	Height   int
+	Variants []int
+	// Sizes is the sizes attribute used with the variants, "100vw" if empty.
+	Sizes string
+	// WebP is true if the image and its variants have WebP copies, see
+	// ImageWebPPath, offered to browsers supporting them.
+	WebP bool
+}
+
+// ImageWebPPath returns the path of the WebP copy of an image, e.g.,
+// "img/a.png" becomes "img/a.png.webp".
+func ImageWebPPath(p string) string {
+	return p + ".webp"
+}
+
+// ImageResolver looks up the image referenced by a document, it returns nil
+// for unknown images.
+type ImageResolver func(ref string) *ImageInfo
+
+// ImageVariantPath returns the path of the copy of an image resized to the
+// given width, e.g., "img/a.png" becomes "img/a-480w.png".
+func ImageVariantPath(p string, width int) string {
+	ext := path.Ext(p)
+	return fmt.Sprintf("%s-%dw%s", strings.TrimSuffix(p, ext), width, ext)
+}
+
+// SetImageResolver sets the lookup for the size and variants of referenced
+// images, used to emit width, height and srcset attributes.
+func (r *Renderer) SetImageResolver(resolver ImageResolver) {
+	r.imageResolver = resolver
+}
+
+// parseImageAttrs parses and removes an attribute list following an image,
+// e.g., ![alt](a.png){width=50% .wide}. It supports key=value pairs, .class
+// and #id.
+func parseImageAttrs(n *ast.Image) map[string]string {
+	t, ok := ast.GetNextNode(n).(*ast.Text)
+	if !ok || !bytes.HasPrefix(t.Literal, imageAttrsOpen) {
+		return nil
+	}
+	end := bytes.Index(t.Literal, imageAttrsClose)
+	if end < 0 {
+		return nil
+	}
+
+	attrs := map[string]string{}
+	for _, f := range strings.Fields(string(t.Literal[1:end])) {
+		switch {
+		case strings.HasPrefix(f, "."):
+			attrs["class"] = strings.TrimSpace(attrs["class"] + " " + f[1:])
+		case strings.HasPrefix(f, "#"):
+			attrs["id"] = f[1:]
+		default:
+			k, v, found := strings.Cut(f, "=")
+			if !found {
+				return nil
+			}
+			attrs[k] = strings.Trim(v, `"'`)
+		}
+	}
+	t.Literal = t.Literal[end+1:]
+
+	return attrs
+}
+
+// cssLength turns an attribute value into a CSS length, a plain number is
+// in pixels.
+func cssLength(v string) string {
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		return v + "px"
+	}
+	return v
+}
+
+// prepareImage sets the attributes of an image, and returns the info of
+// the image if known.
+func (r *Renderer) prepareImage(n *ast.Image) *ImageInfo {
+	if n.Attribute == nil {
+		n.Attribute = &ast.Attribute{}
+	}
+	if n.Attribute.Attrs == nil {
+		n.Attribute.Attrs = map[string][]byte{}
+	}
+	set := func(k, v string) {
+		n.Attribute.Attrs[k] = []byte(html.EscapeString(v))
+	}
+
+	ref := string(n.Destination)
+	var info *ImageInfo
+	if r.imageResolver != nil && !isExternalLink(ref) {
+		info = r.imageResolver(ref)
+	}
+
+	r.state.include("img", imageCss, "")
+	n.Attribute.Classes = append(n.Attribute.Classes, []byte("img"))
+
+	// Images are full width by default, see imageCss.
+	style := ""
+	attrs := parseImageAttrs(n)
+	if w, found := attrs["width"]; found {
+		style += "width:" + cssLength(w) + ";"
+		delete(attrs, "width")
+	}
+	if h, found := attrs["height"]; found {
+		style += "height:" + cssLength(h) + ";"
+		delete(attrs, "height")
+	}
+	for k, v := range attrs {
+		switch k {
+		case "id":
+			n.Attribute.ID = []byte(html.EscapeString(v))
+		case "class":
+			n.Attribute.Classes = append(
+				n.Attribute.Classes,
+				[]byte(html.EscapeString(v)),
+			)
+		case "style":
+			style += v
+		default:
+			set(k, v)
+		}
+	}
+	if style != "" {
+		set("style", style)
+	}
+
+	if info == nil {
+		return nil
+	}
+
+	set("width", strconv.Itoa(info.Width))
+	set("height", strconv.Itoa(info.Height))
+	if len(info.Variants) == 0 {
+		return info
+	}
+
+	set("srcset", r.imageSrcset(ref, info, ""))
+	set("sizes", imageSizes(info))
+
+	return info
+}
+
+// imageSrcset returns the srcset of an image and its variants, of their
+// copies of the given format if not empty.
+func (r *Renderer) imageSrcset(ref string, info *ImageInfo, format string) string {
+	src := func(p string) string {
+		if format == "webp" {
+			p = ImageWebPPath(p)
+		}
+		return r.rewriteRef(p)
+	}
+
+	var srcset []string
+	for _, w := range info.Variants {
+		srcset = append(srcset, fmt.Sprintf(
+			"%s %dw",
+			src(ImageVariantPath(ref, w)),
+			w,
+		))
+	}
+	srcset = append(srcset, fmt.Sprintf("%s %dw", src(ref), info.Width))
+
+	return strings.Join(srcset, ", ")
+}
+
+func imageSizes(info *ImageInfo) string {
+	if info.Sizes == "" {
+		return defaultImageSizes
+	}
+	return info.Sizes
+}
+
+// pictureOpen starts a picture element offering the WebP copies of an image
+// before the image itself.
+func (r *Renderer) pictureOpen(w io.Writer, ref string, info *ImageInfo) {
+	fmt.Fprintf(
+		w,
+		`<picture><source type="image/webp" srcset="%s" sizes="%s">`,
+		html.EscapeString(r.imageSrcset(ref, info, "webp")),
+		html.EscapeString(imageSizes(info)),
+	)
+}
@@ -0,0 +1,34 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRenderImageAttrs(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte(
+		"![a](a.png){width=50% #fig .wide title=\"x\"} text\n\n![b](b.png)\n",
+	)))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<p><img id=\"fig\" class=\"img wide\" style=\"width:50%;\" title=\"x\" src=\"a.png\" alt=\"a\" /> text</p>\n\n"+
+			"<p><img class=\"img\" src=\"b.png\" alt=\"b\" /></p>\n",
+		string(doc.Html),
+	)
+
+	r.SetImageResolver(func(ref string) *ImageInfo {
+		return &ImageInfo{Width: 800, Height: 600}
+	})
+	doc, err = r.Render(NewParser().Parse([]byte("![a](a.png){width=300}\n")))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<p><img class=\"img\" height=\"600\" style=\"width:300px;\" width=\"800\" src=\"a.png\" alt=\"a\" /></p>\n",
+		string(doc.Html),
+	)
+}
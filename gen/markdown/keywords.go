@@ -1,7 +1,8 @@
 package markdown
 
 import (
-	"github.com/iamjinlei/proteus/gen/color"
+	"sort"
+
 	"github.com/iamjinlei/proteus/gen/keyword"
 )
 
@@ -11,15 +12,15 @@ type Keyword struct {
 }
 
 type Keywords struct {
-	colorMap map[string]color.Color
+	colorCss func(kind string) (string, string)
 	index    map[keyword.Type][]*Keyword
 }
 
 func newKeywords(
-	colorMap map[string]color.Color,
+	colorCss func(kind string) (string, string),
 ) *Keywords {
 	return &Keywords{
-		colorMap: colorMap,
+		colorCss: colorCss,
 		index:    map[keyword.Type][]*Keyword{},
 	}
 }
@@ -39,6 +40,22 @@ func (k *Keywords) Get(t keyword.Type) []*Keyword {
 	return k.index[t]
 }
 
-func (k *Keywords) Color(t keyword.Type) color.Color {
-	return k.colorMap[string(t)]
+// ColorCss returns the CSS values of the color of a keyword type and of the
+// text color on it. Palette colors are referred to by their CSS variables,
+// so that they follow the dark palette.
+func (k *Keywords) ColorCss(t keyword.Type) (string, string) {
+	return k.colorCss(string(t))
+}
+
+// Types returns the types that have keywords, in alphabetical order.
+func (k *Keywords) Types() []keyword.Type {
+	var types []keyword.Type
+	for t, _ := range k.index {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i] < types[j]
+	})
+
+	return types
 }
@@ -1,11 +1,73 @@
 package markdown
 
-import "fmt"
+import (
+	"errors"
+	"html"
+	"net/url"
+	"strings"
+)
 
-func baiduBaike(target string) string {
-	return fmt.Sprintf("https://baike.baidu.com/item/%s", target)
+const (
+	linkTermPlaceholder  = "{term}"
+	linkQueryPlaceholder = "{query}"
+)
+
+var (
+	ErrInvalidLinkProvider = errors.New("invalid link provider url template")
+
+	// DefaultLinkProviders maps the value of a highlight <mark> attribute,
+	// e.g., <mark name="wiki">, to a lookup URL template. In a template,
+	// {term} is replaced by the path escaped term and {query} by the query
+	// escaped term.
+	DefaultLinkProviders = map[string]string{
+		"baike":  "https://baike.baidu.com/item/{term}",
+		"baidu":  "https://baike.baidu.com/item/{term}",
+		"wikicn": "https://zh.wikipedia.org/zh-cn/{term}",
+		"wiki":   "https://en.wikipedia.org/wiki/{term}",
+		"mdn":    "https://developer.mozilla.org/en-US/search?q={query}",
+		"godoc":  "https://pkg.go.dev/search?q={query}",
+	}
+)
+
+func validateLinkTemplate(tmpl string) error {
+	if !strings.Contains(tmpl, linkTermPlaceholder) &&
+		!strings.Contains(tmpl, linkQueryPlaceholder) {
+		return ErrInvalidLinkProvider
+	}
+
+	u, err := url.Parse(lookupURL(tmpl, "term"))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return ErrInvalidLinkProvider
+	}
+
+	return nil
+}
+
+func lookupURL(tmpl string, term string) string {
+	return strings.NewReplacer(
+		linkTermPlaceholder, url.PathEscape(term),
+		linkQueryPlaceholder, url.QueryEscape(term),
+	).Replace(tmpl)
 }
 
-func wikipediaCn(target string) string {
-	return fmt.Sprintf("https://zh.wikipedia.org/zh-cn/%s", target)
+// lookupTerm turns the rendered content of a highlight into the plain text
+// term to look up.
+func lookupTerm(content string) string {
+	var b strings.Builder
+	inTag := false
+	for _, c := range content {
+		switch {
+		case c == '<':
+			inTag = true
+		case c == '>':
+			inTag = false
+		case !inTag:
+			b.WriteRune(c)
+		}
+	}
+
+	return strings.TrimSpace(html.UnescapeString(b.String()))
 }
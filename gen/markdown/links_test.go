@@ -0,0 +1,35 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestLinkProviders(t *testing.T) {
+	require.Equal(
+		t,
+		"https://en.wikipedia.org/wiki/C++%20&%20Go",
+		lookupURL(DefaultLinkProviders["wiki"], lookupTerm("<em>C++</em> &amp; Go")),
+	)
+	require.Equal(
+		t,
+		"https://pkg.go.dev/search?q=net%2Fhttp+client",
+		lookupURL(DefaultLinkProviders["godoc"], "net/http client"),
+	)
+
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	require.ErrorIs(t, r.RegisterLinkProvider("bad", "https://wiki.local/"), ErrInvalidLinkProvider)
+	require.ErrorIs(t, r.RegisterLinkProvider("bad", "/wiki/{term}"), ErrInvalidLinkProvider)
+	require.NoError(t, r.RegisterLinkProvider("intra", "https://wiki.local/page/{term}"))
+
+	doc, err := r.Render(NewParser().Parse([]byte(`<mark b="intra">北京 & co</mark>`)))
+	require.NoError(t, err)
+	require.True(t, strings.Contains(
+		string(doc.Html),
+		`<a href="https://wiki.local/page/%E5%8C%97%E4%BA%AC%20&amp;%20co" class="hl_link">`,
+	))
+}
@@ -0,0 +1,129 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/html"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+const (
+	defaultDefinitionListCss = `
+dl dt {
+	font-weight: bold;
+	margin-top: 0.8em;
+}
+dl dd {
+	margin: 0.2em 0 0.2em 1.5em;
+	padding-left: 0.8em;
+	border-left: 3px solid {{ .Palette.LightGray }};
+}
+`
+
+	defaultTaskListCss = `
+li.task_item {
+	list-style-type: none;
+}
+li.task_item > input[type="checkbox"] {
+	margin: 0 0.4em 0 -1.4em;
+	vertical-align: middle;
+	accent-color: {{ .Palette.Green }};
+}
+`
+)
+
+var (
+	taskMarkerTodo = []byte("[ ] ")
+	taskMarkerDone = [][]byte{[]byte("[x] "), []byte("[X] ")}
+)
+
+// parseTaskMarker checks if a list item starts with a GitHub style task
+// marker, i.e., "[ ] " or "[x] ". The marker is removed from the text.
+func parseTaskMarker(n *ast.ListItem) (done bool, ok bool) {
+	if n.RefLink != nil || n.ListFlags&ast.ListTypeDefinition != 0 ||
+		n.ListFlags&ast.ListTypeTerm != 0 || len(n.Children) == 0 {
+		return false, false
+	}
+	p, ok := n.Children[0].(*ast.Paragraph)
+	if !ok || len(p.Children) == 0 {
+		return false, false
+	}
+	t, ok := p.Children[0].(*ast.Text)
+	if !ok {
+		return false, false
+	}
+
+	if bytes.HasPrefix(t.Literal, taskMarkerTodo) {
+		t.Literal = t.Literal[len(taskMarkerTodo):]
+		return false, true
+	}
+	for _, m := range taskMarkerDone {
+		if bytes.HasPrefix(t.Literal, m) {
+			t.Literal = t.Literal[len(m):]
+			return true, true
+		}
+	}
+
+	return false, false
+}
+
+func (r *Renderer) renderList(
+	w io.Writer,
+	n *ast.List,
+	entering bool,
+) ast.WalkStatus {
+	if entering && n.ListFlags&ast.ListTypeDefinition != 0 {
+		r.state.include("definition_list", definitionListCss(r.palette), "")
+	}
+
+	if entering && n.IsFootnotesList {
+		r.includeFootnotes()
+	}
+
+	return r.renderNodeDefault(w, n, entering)
+}
+
+func (r *Renderer) renderListItem(
+	w io.Writer,
+	n *ast.ListItem,
+	entering bool,
+) ast.WalkStatus {
+	if !entering {
+		if r.state.tasks[n] {
+			fmt.Fprint(w, "</li>")
+			r.state.renderer.CR(w)
+			return ast.GoToNext
+		}
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	done, ok := parseTaskMarker(n)
+	if !ok {
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	r.state.tasks[n] = true
+	r.state.include("task_list", taskListCss(r.palette), "")
+	if html.ListItemOpenCR(n) {
+		r.state.renderer.CR(w)
+	}
+	checked := ""
+	if done {
+		checked = " checked"
+	}
+	fmt.Fprintf(w, `<li class="task_item"><input type="checkbox" disabled%s>`, checked)
+
+	return ast.GoToNext
+}
+
+func definitionListCss(palette color.Palette) string {
+	return palette.ReplaceIn(defaultDefinitionListCss)
+}
+
+func taskListCss(palette color.Palette) string {
+	return palette.ReplaceIn(defaultTaskListCss)
+}
@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRenderTaskList(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte(
+		"- [ ] todo *a*\n- [x] done\n- plain\n",
+	)))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<ul>\n"+
+			"<li class=\"task_item\"><input type=\"checkbox\" disabled>todo <em>a</em></li>\n"+
+			"<li class=\"task_item\"><input type=\"checkbox\" disabled checked>done</li>\n"+
+			"<li>plain</li>\n"+
+			"</ul>\n",
+		string(doc.Html),
+	)
+	require.Contains(t, string(doc.Css), "li.task_item")
+}
+
+func TestRenderDefinitionList(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte("Term\n: Definition.\n")))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<dl>\n<dt>Term</dt>\n<dd>Definition.</dd>\n</dl>\n",
+		string(doc.Html),
+	)
+	require.Contains(t, string(doc.Css), "dl dd")
+
+	p, err := NewParserWithExtensions([]string{"tables"})
+	require.NoError(t, err)
+	doc, err = r.Render(p.Parse([]byte("Term\n: Definition.\n")))
+	require.NoError(t, err)
+	require.Equal(t, "<p>Term\n: Definition.</p>\n", string(doc.Html))
+	require.Empty(t, doc.Css)
+
+	_, err = NewParserWithExtensions([]string{"unknown"})
+	require.ErrorIs(t, err, ErrUnknownExtension)
+}
+
+func TestRenderFootnotes(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	doc, err := r.Render(NewParser().Parse([]byte(
+		"Text[^1].\n\n[^1]: The note.\n",
+	)))
+	require.NoError(t, err)
+	require.Contains(
+		t,
+		string(doc.Html),
+		`<sup class="footnote-ref" id="fnref:1"><a href="#fn:1">1</a></sup>`,
+	)
+	require.Contains(
+		t,
+		string(doc.Html),
+		`<li id="fn:1">The note. <a class="footnote-return" href="#fnref:1">`,
+	)
+	require.Contains(t, string(doc.Css), ".footnote_preview")
+	require.Contains(t, string(doc.Js), "footnote_preview")
+	require.Empty(t, doc.InternalRefs)
+}
@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/gomarkdown/markdown/ast"
+)
+
+// MathMode selects how math expressions are rendered.
+type MathMode string
+
+const (
+	// MathMathML converts LaTeX math to MathML at build time, pages work
+	// offline and need no script.
+	MathMathML MathMode = "mathml"
+	// MathKaTeX leaves LaTeX math in the page and loads KaTeX to render it
+	// in the browser.
+	MathKaTeX MathMode = "katex"
+)
+
+// ValidMathMode reports whether m is a supported math mode.
+func ValidMathMode(m MathMode) bool {
+	return m == MathMathML || m == MathKaTeX
+}
+
+const (
+	mathMLCss = `
+.math_block {
+	margin: 1em 0;
+	overflow-x: auto;
+	overflow-y: hidden;
+}
+`
+
+	katexVersion = "0.16.11"
+
+	katexJs = `
+(function() {
+	var base = "https://cdn.jsdelivr.net/npm/katex@` + katexVersion + `/dist/";
+	var css = document.createElement("link");
+	css.rel = "stylesheet";
+	css.href = base + "katex.min.css";
+	document.head.appendChild(css);
+
+	function load(src, onload) {
+		var s = document.createElement("script");
+		s.src = src;
+		s.defer = true;
+		s.onload = onload;
+		document.head.appendChild(s);
+	}
+	load(base + "katex.min.js", function() {
+		load(base + "contrib/auto-render.min.js", function() {
+			renderMathInElement(document.body, {
+				delimiters: [
+					{left: "\\[", right: "\\]", display: true},
+					{left: "\\(", right: "\\)", display: false}
+				]
+			});
+		});
+	});
+})();
+`
+)
+
+func (r *Renderer) renderMath(
+	w io.Writer,
+	n *ast.Math,
+	entering bool,
+) ast.WalkStatus {
+	if r.state.math == MathKaTeX {
+		r.state.includeInline("katex", katexJs)
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	fmt.Fprint(w, latexToMathML(string(n.Literal), false))
+	return ast.GoToNext
+}
+
+func (r *Renderer) renderMathBlock(
+	w io.Writer,
+	n *ast.MathBlock,
+	entering bool,
+) ast.WalkStatus {
+	if r.state.math == MathKaTeX {
+		r.state.includeInline("katex", katexJs)
+		return r.renderNodeDefault(w, n, entering)
+	}
+
+	if entering {
+		r.state.include("mathml", mathMLCss, "")
+		fmt.Fprintf(
+			w,
+			`<div class="math_block">%s</div>`,
+			latexToMathML(string(n.Literal), true),
+		)
+	}
+	return ast.GoToNext
+}
@@ -0,0 +1,522 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// A small LaTeX to MathML converter covering the commonly used subset of
+// LaTeX math: scripts, fractions, roots, greek letters, operators, accents,
+// fonts, \left/\right delimiters and matrix like environments. Unknown
+// commands are rendered as text so that a page never fails to render because
+// of math.
+
+var (
+	mathGreek = map[string]string{
+		"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ",
+		"epsilon": "ϵ", "varepsilon": "ε", "zeta": "ζ", "eta": "η",
+		"theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+		"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π",
+		"varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+		"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+		"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+		"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ",
+		"Xi": "Ξ", "Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ",
+		"Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+		"infty": "∞", "partial": "∂", "nabla": "∇", "hbar": "ℏ",
+		"ell": "ℓ", "emptyset": "∅", "aleph": "ℵ",
+	}
+
+	mathOperators = map[string]string{
+		"times": "×", "cdot": "⋅", "div": "÷", "pm": "±", "mp": "∓",
+		"ast": "∗", "star": "⋆", "circ": "∘", "bullet": "∙",
+		"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠",
+		"ne": "≠", "approx": "≈", "equiv": "≡", "sim": "∼",
+		"simeq": "≃", "cong": "≅", "propto": "∝", "ll": "≪", "gg": "≫",
+		"in": "∈", "notin": "∉", "ni": "∋", "subset": "⊂",
+		"supset": "⊃", "subseteq": "⊆", "supseteq": "⊇", "cup": "∪",
+		"cap": "∩", "setminus": "∖", "wedge": "∧", "land": "∧",
+		"vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+		"forall": "∀", "exists": "∃", "to": "→", "rightarrow": "→",
+		"leftarrow": "←", "gets": "←", "leftrightarrow": "↔",
+		"Rightarrow": "⇒", "Leftarrow": "⇐", "Leftrightarrow": "⇔",
+		"implies": "⟹", "iff": "⟺", "mapsto": "↦", "uparrow": "↑",
+		"downarrow": "↓", "mid": "∣", "parallel": "∥", "perp": "⊥",
+		"angle": "∠", "ldots": "…", "cdots": "⋯", "vdots": "⋮",
+		"ddots": "⋱", "dots": "…", "prime": "′", "oplus": "⊕",
+		"otimes": "⊗", "langle": "⟨", "rangle": "⟩", "lfloor": "⌊",
+		"rfloor": "⌋", "lceil": "⌈", "rceil": "⌉", "vert": "|",
+		"Vert": "‖", "lbrace": "{", "rbrace": "}",
+	}
+
+	// Big operators take their scripts above and below in display mode.
+	mathBigOperators = map[string]string{
+		"sum": "∑", "prod": "∏", "coprod": "∐", "bigcup": "⋃",
+		"bigcap": "⋂", "bigoplus": "⨁", "bigotimes": "⨂",
+	}
+
+	mathIntegrals = map[string]string{
+		"int": "∫", "iint": "∬", "iiint": "∭", "oint": "∮",
+	}
+
+	mathFunctions = map[string]bool{
+		"sin": true, "cos": true, "tan": true, "cot": true, "sec": true,
+		"csc": true, "arcsin": true, "arccos": true, "arctan": true,
+		"sinh": true, "cosh": true, "tanh": true, "log": true, "ln": true,
+		"lg": true, "exp": true, "det": true, "dim": true, "ker": true,
+		"deg": true, "gcd": true, "arg": true, "hom": true, "Pr": true,
+	}
+
+	// Function like operators that take their scripts below in display
+	// mode.
+	mathLimits = map[string]bool{
+		"lim": true, "limsup": true, "liminf": true, "max": true,
+		"min": true, "sup": true, "inf": true,
+	}
+
+	mathAccents = map[string]string{
+		"hat": "^", "widehat": "^", "bar": "¯", "overline": "¯",
+		"vec": "→", "dot": "˙", "ddot": "¨", "tilde": "~",
+		"widetilde": "~", "overrightarrow": "→",
+	}
+
+	mathFonts = map[string]string{
+		"mathbf": "bold", "mathit": "italic", "mathrm": "normal",
+		"mathbb": "double-struck", "mathcal": "script",
+		"mathfrak": "fraktur", "mathsf": "sans-serif",
+		"mathtt": "monospace", "boldsymbol": "bold",
+	}
+
+	mathSpaces = map[string]string{
+		",": "0.1667em", ":": "0.2222em", ";": "0.2778em",
+		" ": "0.25em", "quad": "1em", "qquad": "2em", "!": "-0.1667em",
+	}
+
+	mathEnvFences = map[string][2]string{
+		"matrix":  {"", ""},
+		"pmatrix": {"(", ")"},
+		"bmatrix": {"[", "]"},
+		"Bmatrix": {"{", "}"},
+		"vmatrix": {"|", "|"},
+		"Vmatrix": {"‖", "‖"},
+		"cases":   {"{", ""},
+		"aligned": {"", ""},
+		"align":   {"", ""},
+		"align*":  {"", ""},
+		"array":   {"", ""},
+	}
+)
+
+type mathParser struct {
+	src     []rune
+	pos     int
+	display bool
+}
+
+// latexToMathML converts a LaTeX math expression to a MathML element.
+func latexToMathML(tex string, display bool) string {
+	p := &mathParser{
+		src:     []rune(tex),
+		display: display,
+	}
+	body := p.parseRow(nil)
+	for p.pos < len(p.src) {
+		// Unbalanced closing brace, skip it and continue.
+		p.pos++
+		body += p.parseRow(nil)
+	}
+
+	mode := "inline"
+	if display {
+		mode = "block"
+	}
+	return fmt.Sprintf(
+		`<math xmlns="http://www.w3.org/1998/Math/MathML" display="%s"><semantics><mrow>%s</mrow><annotation encoding="application/x-tex">%s</annotation></semantics></math>`,
+		mode,
+		body,
+		html.EscapeString(strings.TrimSpace(tex)),
+	)
+}
+
+func (p *mathParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *mathParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *mathParser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// peekCommand returns the command name at the current position without
+// consuming it.
+func (p *mathParser) peekCommand() string {
+	if p.peek() != '\\' {
+		return ""
+	}
+	save := p.pos
+	name := p.readCommand()
+	p.pos = save
+	return name
+}
+
+// readCommand consumes a command after the backslash. A command is either a
+// run of letters or a single non-letter character.
+func (p *mathParser) readCommand() string {
+	p.pos++ // backslash
+	if p.eof() {
+		return ""
+	}
+
+	start := p.pos
+	if !unicode.IsLetter(p.peek()) {
+		p.pos++
+		return string(p.src[start:p.pos])
+	}
+	for !p.eof() && unicode.IsLetter(p.peek()) {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// readRawGroup consumes a {...} group and returns its raw content.
+func (p *mathParser) readRawGroup() string {
+	p.skipSpace()
+	if p.peek() != '{' {
+		return ""
+	}
+	p.pos++
+	start, depth := p.pos, 1
+	for !p.eof() {
+		switch p.peek() {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 {
+			s := string(p.src[start:p.pos])
+			p.pos++
+			return s
+		}
+		p.pos++
+	}
+	return string(p.src[start:])
+}
+
+// readRawOption consumes a [...] optional argument and returns it converted.
+func (p *mathParser) readRawOption() string {
+	p.pos++ // opening bracket
+	start := p.pos
+	for !p.eof() && p.peek() != ']' {
+		p.pos++
+	}
+	sub := &mathParser{
+		src:     p.src[start:p.pos],
+		display: p.display,
+	}
+	if !p.eof() {
+		p.pos++
+	}
+	return sub.parseRow(nil)
+}
+
+// isRowEnd checks if the current position ends a row, stop lists commands
+// that end it in addition to the closing brace.
+func (p *mathParser) isRowEnd(stop map[string]bool) bool {
+	if p.eof() || p.peek() == '}' {
+		return true
+	}
+	if p.peek() == '&' && stop["&"] {
+		return true
+	}
+	if cmd := p.peekCommand(); cmd != "" && stop[cmd] {
+		return true
+	}
+	return false
+}
+
+func (p *mathParser) parseRow(stop map[string]bool) string {
+	var b strings.Builder
+	for {
+		p.skipSpace()
+		if p.isRowEnd(stop) {
+			break
+		}
+		b.WriteString(p.parseScripted())
+	}
+	return b.String()
+}
+
+// parseScripted parses an atom followed by optional sub/superscripts.
+func (p *mathParser) parseScripted() string {
+	base, limits := p.parseAtom()
+
+	var sub, sup string
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '_':
+			p.pos++
+			sub = p.parseArg()
+			continue
+		case '^':
+			p.pos++
+			sup = p.parseArg()
+			continue
+		case '\'':
+			p.pos++
+			sup += "<mo>′</mo>"
+			continue
+		}
+		break
+	}
+
+	under, over := "msub", "msup"
+	both := "msubsup"
+	if limits && p.display {
+		under, over, both = "munder", "mover", "munderover"
+	}
+	switch {
+	case sub != "" && sup != "":
+		return fmt.Sprintf("<%s>%s<mrow>%s</mrow><mrow>%s</mrow></%s>", both, base, sub, sup, both)
+	case sub != "":
+		return fmt.Sprintf("<%s>%s<mrow>%s</mrow></%s>", under, base, sub, under)
+	case sup != "":
+		return fmt.Sprintf("<%s>%s<mrow>%s</mrow></%s>", over, base, sup, over)
+	}
+	return base
+}
+
+// parseArg parses a command argument or script, i.e., a group or a single
+// atom.
+func (p *mathParser) parseArg() string {
+	p.skipSpace()
+	if p.peek() == '{' {
+		p.pos++
+		s := p.parseRow(nil)
+		if !p.eof() {
+			p.pos++ // closing brace
+		}
+		return s
+	}
+	if p.eof() {
+		return ""
+	}
+	s, _ := p.parseAtom()
+	return s
+}
+
+// parseAtom parses a single element. The returned flag tells if scripts of
+// the element are placed as limits in display mode.
+func (p *mathParser) parseAtom() (string, bool) {
+	c := p.peek()
+	switch {
+	case c == '{':
+		p.pos++
+		s := p.parseRow(nil)
+		if !p.eof() {
+			p.pos++
+		}
+		return "<mrow>" + s + "</mrow>", false
+	case c == '\\':
+		return p.parseCommand()
+	case unicode.IsDigit(c) || c == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1]):
+		start := p.pos
+		for !p.eof() && (unicode.IsDigit(p.peek()) || p.peek() == '.') {
+			p.pos++
+		}
+		return mathElem("mn", string(p.src[start:p.pos])), false
+	case unicode.IsLetter(c):
+		p.pos++
+		return mathElem("mi", string(c)), false
+	}
+
+	p.pos++
+	return mathElem("mo", string(c)), false
+}
+
+func (p *mathParser) parseCommand() (string, bool) {
+	name := p.readCommand()
+
+	if v, found := mathGreek[name]; found {
+		return mathElem("mi", v), false
+	}
+	if v, found := mathOperators[name]; found {
+		return mathElem("mo", v), false
+	}
+	if v, found := mathBigOperators[name]; found {
+		return fmt.Sprintf(`<mo largeop="true" movablelimits="true">%s</mo>`, v), true
+	}
+	if v, found := mathIntegrals[name]; found {
+		return fmt.Sprintf(`<mo largeop="true">%s</mo>`, v), false
+	}
+	if mathFunctions[name] {
+		return fmt.Sprintf(`<mi mathvariant="normal">%s</mi>`, name), false
+	}
+	if mathLimits[name] {
+		return fmt.Sprintf(`<mo movablelimits="true">%s</mo>`, name), true
+	}
+	if v, found := mathAccents[name]; found {
+		return fmt.Sprintf(
+			`<mover accent="true"><mrow>%s</mrow><mo stretchy="true">%s</mo></mover>`,
+			p.parseArg(),
+			v,
+		), false
+	}
+	if v, found := mathFonts[name]; found {
+		return fmt.Sprintf(
+			`<mstyle mathvariant="%s"><mrow>%s</mrow></mstyle>`,
+			v,
+			p.parseArg(),
+		), false
+	}
+	if v, found := mathSpaces[name]; found {
+		return fmt.Sprintf(`<mspace width="%s"/>`, v), false
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num := p.parseArg()
+		den := p.parseArg()
+		return fmt.Sprintf("<mfrac><mrow>%s</mrow><mrow>%s</mrow></mfrac>", num, den), false
+	case "binom":
+		n := p.parseArg()
+		k := p.parseArg()
+		return fmt.Sprintf(
+			`<mrow><mo>(</mo><mfrac linethickness="0"><mrow>%s</mrow><mrow>%s</mrow></mfrac><mo>)</mo></mrow>`,
+			n,
+			k,
+		), false
+	case "sqrt":
+		p.skipSpace()
+		if p.peek() == '[' {
+			index := p.readRawOption()
+			return fmt.Sprintf("<mroot><mrow>%s</mrow><mrow>%s</mrow></mroot>", p.parseArg(), index), false
+		}
+		return fmt.Sprintf("<msqrt><mrow>%s</mrow></msqrt>", p.parseArg()), false
+	case "underline":
+		return fmt.Sprintf(`<munder><mrow>%s</mrow><mo stretchy="true">_</mo></munder>`, p.parseArg()), false
+	case "text", "textrm", "mbox", "operatorname":
+		text := p.readRawGroup()
+		if name == "operatorname" {
+			return fmt.Sprintf(`<mi mathvariant="normal">%s</mi>`, html.EscapeString(text)), false
+		}
+		return mathElem("mtext", text), false
+	case "left", "right", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr":
+		return p.parseDelimiter(name), false
+	case "begin":
+		return p.parseEnvironment(p.readRawGroup()), false
+	case "\\":
+		// Line break outside of an environment.
+		return `<mspace linebreak="newline"/>`, false
+	case "{", "}", "%", "$", "#", "&", "_":
+		return mathElem("mo", name), false
+	case "|":
+		return mathElem("mo", "‖"), false
+	}
+
+	return mathElem("mtext", "\\"+name), false
+}
+
+// parseDelimiter parses the delimiter after \left, \right or a sizing
+// command. For \left, the content up to the matching \right is wrapped in a
+// row.
+func (p *mathParser) parseDelimiter(cmd string) string {
+	p.skipSpace()
+	delim := ""
+	if p.peek() == '\\' {
+		name := p.readCommand()
+		if v, found := mathOperators[name]; found {
+			delim = v
+		} else if name == "{" || name == "}" {
+			delim = name
+		} else if name == "|" {
+			delim = "‖"
+		}
+	} else if !p.eof() {
+		delim = string(p.peek())
+		p.pos++
+	}
+	if delim == "." {
+		delim = ""
+	}
+
+	mo := ""
+	if delim != "" {
+		mo = fmt.Sprintf(`<mo stretchy="true" fence="true">%s</mo>`, html.EscapeString(delim))
+	}
+	if cmd != "left" {
+		return mo
+	}
+
+	body := p.parseRow(map[string]bool{"right": true})
+	closing := ""
+	if p.peekCommand() == "right" {
+		p.readCommand()
+		closing = p.parseDelimiter("right")
+	}
+	return "<mrow>" + mo + body + closing + "</mrow>"
+}
+
+func (p *mathParser) parseEnvironment(env string) string {
+	stop := map[string]bool{"&": true, "\\": true, "end": true}
+	if env == "array" {
+		// Skip the column spec.
+		p.readRawGroup()
+	}
+
+	var rows []string
+	var cells []string
+	for {
+		cells = append(cells, "<mtd>"+p.parseRow(stop)+"</mtd>")
+		if p.peek() == '&' {
+			p.pos++
+			continue
+		}
+
+		cmd := ""
+		if !p.eof() && p.peek() != '}' {
+			cmd = p.readCommand()
+		}
+		// Skip the empty row after a trailing line break.
+		if cmd == "\\" || len(cells) > 1 || cells[0] != "<mtd></mtd>" {
+			rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+		}
+		cells = nil
+		if cmd != "\\" {
+			if cmd == "end" {
+				p.readRawGroup()
+			}
+			break
+		}
+	}
+
+	table := "<mtable>" + strings.Join(rows, "") + "</mtable>"
+	if env == "cases" || strings.HasPrefix(env, "align") {
+		table = `<mtable columnalign="left">` + strings.Join(rows, "") + "</mtable>"
+	}
+
+	fences := mathEnvFences[env]
+	res := table
+	if fences[0] != "" {
+		res = fmt.Sprintf(`<mo fence="true" stretchy="true">%s</mo>`, html.EscapeString(fences[0])) + res
+	}
+	if fences[1] != "" {
+		res += fmt.Sprintf(`<mo fence="true" stretchy="true">%s</mo>`, html.EscapeString(fences[1]))
+	}
+	return "<mrow>" + res + "</mrow>"
+}
+
+func mathElem(tag string, content string) string {
+	return fmt.Sprintf("<%s>%s</%s>", tag, html.EscapeString(content), tag)
+}
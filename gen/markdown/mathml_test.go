@@ -0,0 +1,44 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatexToMathML(t *testing.T) {
+	body := func(tex string, display bool) string {
+		s := latexToMathML(tex, display)
+		s = s[strings.Index(s, "<semantics><mrow>")+len("<semantics><mrow>"):]
+		return s[:strings.Index(s, "</mrow><annotation")]
+	}
+
+	require.Equal(t, "<msup><mi>a</mi><mrow><mn>2</mn></mrow></msup><mo>+</mo><msub><mi>b</mi><mrow><mi>i</mi></mrow></msub>", body("a^2 + b_i", false))
+	require.Equal(t, "<mfrac><mrow><mn>1</mn></mrow><mrow><mi>α</mi></mrow></mfrac>", body(`\frac{1}{\alpha}`, false))
+	require.Equal(t, "<mroot><mrow><mi>x</mi></mrow><mrow><mn>3</mn></mrow></mroot>", body(`\sqrt[3]{x}`, false))
+	require.Equal(
+		t,
+		`<munderover><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mrow><mi>n</mi></mrow></munderover>`,
+		body(`\sum_{i=1}^n`, true),
+	)
+	require.Equal(
+		t,
+		`<msubsup><mo largeop="true" movablelimits="true">∑</mo><mrow><mi>i</mi></mrow><mrow><mi>n</mi></mrow></msubsup>`,
+		body(`\sum_i^n`, false),
+	)
+	require.Equal(
+		t,
+		`<mrow><mo stretchy="true" fence="true">(</mo><mi>x</mi><mo stretchy="true" fence="true">)</mo></mrow>`,
+		body(`\left( x \right)`, false),
+	)
+	require.Equal(
+		t,
+		`<mrow><mo fence="true" stretchy="true">(</mo><mtable><mtr><mtd><mn>1</mn></mtd><mtd><mn>0</mn></mtd></mtr><mtr><mtd><mn>0</mn></mtd><mtd><mn>1</mn></mtd></mtr></mtable><mo fence="true" stretchy="true">)</mo></mrow>`,
+		body(`\begin{pmatrix} 1 & 0 \\ 0 & 1 \\ \end{pmatrix}`, true),
+	)
+	require.Equal(t, `<mtext>if </mtext><mi mathvariant="normal">sin</mi><mi>x</mi><mo>&lt;</mo><mtext>\foo</mtext>`, body(`\text{if }\sin x < \foo`, false))
+
+	require.True(t, strings.Contains(latexToMathML("a<b", true), `display="block"`))
+	require.True(t, strings.Contains(latexToMathML("a<b", true), `<annotation encoding="application/x-tex">a&lt;b</annotation>`))
+}
@@ -1,23 +1,100 @@
 package markdown
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/gomarkdown/markdown/ast"
 	"github.com/gomarkdown/markdown/parser"
 )
 
+var (
+	ErrUnknownExtension = errors.New("unknown markdown extension")
+
+	// DefaultExtensions are the parser extensions used by NewParser. Math
+	// is not listed as it is enabled per page, see Parser.ParseMath.
+	DefaultExtensions = []string{
+		"no_intra_emphasis",
+		"tables",
+		"fenced_code",
+		"autolink",
+		"strikethrough",
+		"space_headings",
+		"heading_ids",
+		"backslash_line_break",
+		"definition_lists",
+		"footnotes",
+		"no_empty_line_before_block",
+	}
+
+	extensions = map[string]parser.Extensions{
+		"no_intra_emphasis":          parser.NoIntraEmphasis,
+		"tables":                     parser.Tables,
+		"fenced_code":                parser.FencedCode,
+		"autolink":                   parser.Autolink,
+		"strikethrough":              parser.Strikethrough,
+		"lax_html_blocks":            parser.LaxHTMLBlocks,
+		"space_headings":             parser.SpaceHeadings,
+		"hard_line_break":            parser.HardLineBreak,
+		"non_blocking_space":         parser.NonBlockingSpace,
+		"tab_size_eight":             parser.TabSizeEight,
+		"footnotes":                  parser.Footnotes,
+		"no_empty_line_before_block": parser.NoEmptyLineBeforeBlock,
+		"heading_ids":                parser.HeadingIDs,
+		"titleblock":                 parser.Titleblock,
+		"backslash_line_break":       parser.BackslashLineBreak,
+		"definition_lists":           parser.DefinitionLists,
+		"ordered_list_start":         parser.OrderedListStart,
+		"attributes":                 parser.Attributes,
+		"super_subscript":            parser.SuperSubscript,
+		"empty_lines_break_list":     parser.EmptyLinesBreakList,
+		"mmark":                      parser.Mmark,
+	}
+)
+
 // Parser parses markdown document.
 type Parser struct {
+	exts parser.Extensions
 }
 
 func NewParser() *Parser {
-	return &Parser{}
+	p, _ := NewParserWithExtensions(DefaultExtensions)
+	return p
+}
+
+// NewParserWithExtensions creates a parser with the named extensions, see
+// DefaultExtensions for the naming.
+func NewParserWithExtensions(names []string) (*Parser, error) {
+	var exts parser.Extensions
+	for _, name := range names {
+		ext, found := extensions[name]
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownExtension, name)
+		}
+		exts |= ext
+	}
+
+	return &Parser{
+		exts: exts,
+	}, nil
 }
 
 func (p *Parser) Parse(src []byte) ast.Node {
-	mdp := parser.NewWithExtensions(
-		parser.CommonExtensions |
-			parser.AutoHeadingIDs |
-			parser.NoEmptyLineBeforeBlock,
-	)
+	return p.parse(src, false)
+}
+
+// ParseMath parses markdown document with $...$ and $$...$$ math
+// expressions. It is opt-in as dollar signs are common in plain text.
+func (p *Parser) ParseMath(src []byte) ast.Node {
+	return p.parse(src, true)
+}
+
+func (p *Parser) parse(src []byte, math bool) ast.Node {
+	exts := p.exts
+	if math {
+		exts |= parser.MathJax
+	}
+
+	mdp := parser.NewWithExtensions(exts)
 	return mdp.Parse(src)
 }
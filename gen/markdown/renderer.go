@@ -2,6 +2,7 @@ package markdown
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
@@ -21,27 +22,51 @@ const (
 	renderNode = false
 )
 
-type Styles struct {
-	Code      string
-	CodeBlock string
-}
+// ToCPlaceholder is written in place of an inline table of contents marker,
+// i.e., a "[[toc]]" paragraph or an <ins type="toc"/> tag. Headings are only
+// known after the whole document is rendered, so it is up to the caller to
+// replace the placeholder.
+const ToCPlaceholder = "<!--proteus:toc-->"
 
 var (
-	defaultStyles = Styles{
-		Code: fmt.Sprintf(
-			"padding-left:0.3em;padding-right:0.3em;background-color:%v;",
-			color.LightGray,
-		),
-		CodeBlock: fmt.Sprintf(
-			"padding:0.1em 1.5em;background-color:%v;",
-			color.LightGray,
-		),
-	}
+	ErrInvalidKeywordType = errors.New("invalid keyword type")
+	// ErrColorNameTaken is returned for a keyword type named after a color,
+	// e.g., "red", which would change the color of all its highlights.
+	ErrColorNameTaken = errors.New("keyword type is a color name")
+
+	tocMarker = []byte("[[toc]]")
+)
+
+const (
+	defaultCodeCss = `
+.code_span {
+	padding-left: 0.3em;
+	padding-right: 0.3em;
+	background-color: {{ .Palette.LightGray }};
+}
+`
+
+	defaultCodeBlockCss = `
+.code_block {
+	padding: 0.1em 1.5em;
+	background-color: {{ .Palette.LightGray }};
+}
+`
 )
 
 type Renderer struct {
 	palette               color.Palette
 	colorMap              map[string]color.Color
+	colorNames            map[string]string
+	tags                  map[string]*tagEntry
+	linkProviders         map[string]string
+	kwTypes               map[keyword.Type]bool
+	diagramCmds           map[string]string
+	diagramCacheDir       string
+	plantUMLServer        string
+	diagramCache          map[string][]byte
+	imageResolver         ImageResolver
+	refRewriter           RefRewriter
 	internalRefHtmlSuffix string
 	lazyImageLoading      bool
 	state                 *renderState
@@ -52,25 +77,108 @@ func NewRenderer(
 	internalRefHtmlSuffix string,
 	lazyImageLoading bool,
 ) *Renderer {
+	// The palette names of the colors, used to refer to their CSS
+	// variables.
+	names := map[string]string{
+		"name": "HighlighterRed",
+		"b":    "HighlighterGreen",
+		"c":    "HighlighterBlue",
+		"d":    "HighlighterYellow",
+		"e":    "HighlighterOrange",
+	}
+	for name := range palette.Colors() {
+		names[strings.ToLower(name)] = name
+	}
 	cm := map[string]color.Color{}
-	types := reflect.TypeOf(palette)
-	vals := reflect.ValueOf(palette)
-	for i := 0; i < types.NumField(); i++ {
-		cm[strings.ToLower(types.Field(i).Name)] = vals.Field(i).Interface().(color.Color)
+	for key, name := range names {
+		cm[key] = palette.Colors()[name]
 	}
 
-	cm["name"] = palette.HighlighterRed
-	cm["b"] = palette.HighlighterGreen
-	cm["c"] = palette.HighlighterBlue
-	cm["d"] = palette.HighlighterYellow
-	cm["e"] = palette.HighlighterOrange
-
-	return &Renderer{
-		palette:               palette,
-		colorMap:              cm,
+	r := &Renderer{
+		palette:       palette,
+		colorMap:      cm,
+		colorNames:    names,
+		tags:          map[string]*tagEntry{},
+		linkProviders: map[string]string{},
+		kwTypes: map[keyword.Type]bool{
+			keyword.Name: true,
+		},
+		diagramCmds:           map[string]string{},
+		diagramCache:          map[string][]byte{},
 		internalRefHtmlSuffix: internalRefHtmlSuffix,
 		lazyImageLoading:      lazyImageLoading,
 	}
+	r.registerBuiltinTags()
+	for name, tmpl := range DefaultLinkProviders {
+		r.linkProviders[name] = tmpl
+	}
+
+	return r
+}
+
+// RegisterLinkProvider adds or replaces a lookup link provider for
+// highlighted terms, see DefaultLinkProviders for the URL template format.
+func (r *Renderer) RegisterLinkProvider(name string, urlTemplate string) error {
+	if err := validateLinkTemplate(urlTemplate); err != nil {
+		return err
+	}
+
+	r.linkProviders[name] = urlTemplate
+	return nil
+}
+
+// RegisterKeywordType adds a keyword type highlighted with the given color.
+// Text marked with <mark type>...</mark> is collected into Doc.Keywords.
+// Types can not be named after colors, see Color.
+func (r *Renderer) RegisterKeywordType(t keyword.Type, c color.Color) error {
+	if !keyword.ValidType(string(t)) {
+		return ErrInvalidKeywordType
+	}
+	if _, found := r.colorMap[string(t)]; found && !r.kwTypes[t] {
+		return ErrColorNameTaken
+	}
+
+	r.kwTypes[t] = true
+	r.colorMap[string(t)] = c
+	delete(r.colorNames, string(t))
+	return nil
+}
+
+// colorCss returns the CSS values of a highlight color and of the text color
+// on it by kind. Palette colors are referred to by their CSS variables, so
+// that they follow the dark palette.
+func (r *Renderer) colorCss(kind string) (string, string) {
+	if name := r.colorNames[kind]; name != "" {
+		return r.palette.Var(name), r.palette.ContrastVar(name)
+	}
+	c := r.colorMap[kind]
+	return c.Hex(), c.Contrast().Hex()
+}
+
+// Color looks up a palette color by its lower cased field name, e.g.,
+// "highlighterblue", or parses a hex color.
+func (r *Renderer) Color(name string) (color.Color, error) {
+	if c, found := r.colorMap[strings.ToLower(name)]; found {
+		return c, nil
+	}
+	return color.Parse(name)
+}
+
+// RefRewriter maps an internal reference to the URL written to the page,
+// e.g., the content hashed name of an asset.
+type RefRewriter func(ref string) string
+
+// SetRefRewriter sets the mapping of internal references to page URLs.
+// Documents still report the original references in Doc.InternalRefs.
+func (r *Renderer) SetRefRewriter(rewriter RefRewriter) {
+	r.refRewriter = rewriter
+}
+
+func (r *Renderer) rewriteRef(ref string) string {
+	if r.refRewriter == nil || ref == "" || isExternalLink(ref) {
+		return ref
+	}
+	return r.refRewriter(ref)
 }
 
 type renderState struct {
@@ -79,12 +187,61 @@ type renderState struct {
 	htmlTagStack *htmlTagStack
 	internalRefs []string
 	ht           *headingTracker
+	slugger      *slugger
+	math         MathMode
 	kws          *Keywords
+	admonitions  map[ast.Node]bool
+	tasks        map[ast.Node]bool
+	figures      map[ast.Node]*figure
+	pictures     map[ast.Node]bool
+	included     map[string]bool
+	css          strings.Builder
+	js           strings.Builder
+	includes     []*Include
 	err          error
 }
 
+// include adds the CSS and JS a rendered element depends on to the document,
+// once per key.
+func (s *renderState) include(key, css, js string) {
+	if s.included[key] {
+		return
+	}
+	s.included[key] = true
+
+	s.css.WriteString(css)
+	s.js.WriteString(js)
+	s.includes = append(s.includes, &Include{
+		Css: template.CSS(css),
+		Js:  template.JS(js),
+	})
+}
+
+// includeInline adds JS with load time side effects to the document, once
+// per key, see Include.Inline.
+func (s *renderState) includeInline(key, js string) {
+	if s.included[key] {
+		return
+	}
+	s.included[key] = true
+
+	s.js.WriteString(js)
+	s.includes = append(s.includes, &Include{
+		Js:     template.JS(js),
+		Inline: true,
+	})
+}
+
+// Render renders a document, math expressions are rendered into MathML.
 func (r *Renderer) Render(root ast.Node) (*Doc, error) {
-	flags := html.CommonFlags
+	return r.RenderWithMath(root, MathMathML)
+}
+
+// RenderWithMath renders a document, with math expressions rendered in the
+// given mode. Math expressions are only present if the document is parsed
+// with Parser.ParseMath.
+func (r *Renderer) RenderWithMath(root ast.Node, math MathMode) (*Doc, error) {
+	flags := html.CommonFlags | html.FootnoteReturnLinks
 	if r.lazyImageLoading {
 		flags |= html.LazyLoadImages
 	}
@@ -92,14 +249,23 @@ func (r *Renderer) Render(root ast.Node) (*Doc, error) {
 	r.state = &renderState{
 		renderer: html.NewRenderer(
 			html.RendererOptions{
-				Flags:          flags,
-				RenderNodeHook: r.render,
+				Flags:                      flags,
+				FootnoteReturnLinkContents: footnoteReturnLink,
+				RenderNodeHook:             r.render,
 			},
 		),
 		htmlTagStack: newHtmlTagStack(),
 		ht:           newHeadingTracker(),
-		kws:          newKeywords(r.colorMap),
+		slugger:      newSlugger(),
+		math:         math,
+		kws:          newKeywords(r.colorCss),
+		admonitions:  map[ast.Node]bool{},
+		tasks:        map[ast.Node]bool{},
+		figures:      map[ast.Node]*figure{},
+		pictures:     map[ast.Node]bool{},
+		included:     map[string]bool{},
 	}
+	claimExplicitHeadingIDs(root, r.state.slugger)
 
 	// Traverse AST using ast.WalkFunc()
 	data := markdown.Render(root, r.state.renderer)
@@ -115,6 +281,9 @@ func (r *Renderer) Render(root ast.Node) (*Doc, error) {
 		InternalRefs: rs.internalRefs,
 		Headings:     rs.ht.getHeadings(),
 		Keywords:     rs.kws,
+		Css:          template.CSS(rs.css.String()),
+		Js:           template.JS(rs.js.String()),
+		Includes:     rs.includes,
 	}, nil
 }
 
@@ -153,16 +322,37 @@ func (r *Renderer) render(
 	// which uses the default writer. Always use r.renderNodeDefault() to
 	// trigger a reentry call to the default Render, with picked writer.
 	switch v := n.(type) {
-	case *ast.Heading:
-		if !entering {
-			break
+	case *ast.Paragraph:
+		if isToCMarker(v) {
+			if entering {
+				fmt.Fprint(w, ToCPlaceholder)
+			}
+			return ast.SkipChildren, renderSkip
 		}
 
-		if len(v.Children) != 1 {
-			break
+		if r.isBlockTagParagraph(v) || r.isFigureParagraph(v) {
+			// Render children without the <p> wrapper, which must not
+			// contain block elements.
+			return ast.GoToNext, renderSkip
 		}
 
-		r.state.ht.add(v.Level, v.HeadingID, string(v.Children[0].(*ast.Text).Literal))
+	case *ast.Heading:
+		return r.renderHeading(w, v, entering), renderSkip
+
+	case *ast.BlockQuote:
+		return r.renderBlockQuote(w, v, entering), renderSkip
+
+	case *ast.List:
+		return r.renderList(w, v, entering), renderSkip
+
+	case *ast.ListItem:
+		return r.renderListItem(w, v, entering), renderSkip
+
+	case *ast.Math:
+		return r.renderMath(w, v, entering), renderSkip
+
+	case *ast.MathBlock:
+		return r.renderMathBlock(w, v, entering), renderSkip
 
 	case *ast.Code:
 		return r.renderCode(w, v, entering), renderSkip
@@ -175,32 +365,33 @@ func (r *Renderer) render(
 			break
 		}
 
-		ref := string(v.Destination)
-		if !isExternalLink(ref) {
-			r.state.internalRefs = append(r.state.internalRefs, ref)
-			v.Destination = []byte(ref + r.internalRefHtmlSuffix)
-		}
-
-	case *ast.Image:
-		if !entering {
+		if v.NoteID != 0 {
+			// Footnote reference.
+			r.includeFootnotes()
 			break
 		}
 
-		if v.Attribute == nil {
-			v.Attribute = &ast.Attribute{}
-		}
-		if v.Attribute.Attrs == nil {
-			v.Attribute.Attrs = map[string][]byte{}
-		}
-		v.Attribute.Attrs["style"] = []byte("width:100%;")
-
 		ref := string(v.Destination)
 		if !isExternalLink(ref) {
 			r.state.internalRefs = append(r.state.internalRefs, ref)
+			if u := r.rewriteRef(ref); u != ref {
+				// A rewritten asset, not a page.
+				v.Destination = []byte(u)
+			} else {
+				v.Destination = []byte(ref + r.internalRefHtmlSuffix)
+			}
 		}
 
+	case *ast.Image:
+		return r.renderImage(w, v, entering), renderSkip
+
 	case *ast.HTMLSpan:
 		return r.processHTMLTag(w, v, entering), renderSkip
+
+	case *ast.HTMLBlock:
+		if entering {
+			v.Literal = r.rewriteHTMLRefs(v.Literal)
+		}
 	}
 
 	/*
@@ -226,6 +417,48 @@ func (r *Renderer) render(
 	return r.renderNodeDefault(w, n, entering), renderSkip
 }
 
+func isToCMarker(p *ast.Paragraph) bool {
+	if len(p.Children) != 1 {
+		return false
+	}
+
+	t, ok := p.Children[0].(*ast.Text)
+	return ok && bytes.Equal(bytes.TrimSpace(t.Literal), tocMarker)
+}
+
+// isBlockTagParagraph checks if a paragraph only holds a tag that renders
+// into a block element, e.g., <ins type="note">...</ins>.
+func (r *Renderer) isBlockTagParagraph(p *ast.Paragraph) bool {
+	var nodes []ast.Node
+	for _, c := range p.Children {
+		if t, ok := c.(*ast.Text); ok && len(bytes.TrimSpace(t.Literal)) == 0 {
+			continue
+		}
+		nodes = append(nodes, c)
+	}
+	if len(nodes) == 0 {
+		return false
+	}
+
+	first, ok := nodes[0].(*ast.HTMLSpan)
+	if !ok {
+		return false
+	}
+	tag, err := parseTag(first.Literal)
+	if err != nil || tag.Data != "ins" {
+		return false
+	}
+	if t := r.tags[getTagAttr(tag, "type")]; t == nil || !t.block {
+		return false
+	}
+
+	if len(nodes) == 1 {
+		return bytes.HasSuffix(first.Literal, htmlSelfClosingTagSuffix)
+	}
+	last, ok := nodes[len(nodes)-1].(*ast.HTMLSpan)
+	return ok && bytes.Equal(last.Literal, htmlClosingTagIns)
+}
+
 func (r *Renderer) renderNodeDefault(
 	w io.Writer,
 	n ast.Node,
@@ -242,7 +475,8 @@ func (r *Renderer) renderCode(
 	n *ast.Code,
 	entering bool,
 ) ast.WalkStatus {
-	fmt.Fprintf(w, `<span style="%v">`, defaultStyles.Code)
+	r.state.include("code", r.palette.ReplaceIn(defaultCodeCss), "")
+	fmt.Fprint(w, `<span class="code_span">`)
 	r.state.renderer.Code(w, n)
 	fmt.Fprintf(w, "</span>")
 	return ast.GoToNext
@@ -253,7 +487,16 @@ func (r *Renderer) renderCodeBlock(
 	n *ast.CodeBlock,
 	entering bool,
 ) ast.WalkStatus {
-	fmt.Fprintf(w, `<div style="%v">`, defaultStyles.CodeBlock)
+	if lang := codeBlockLang(n); isDiagramLang(lang) {
+		r.renderDiagram(w, lang, n.Literal)
+		if r.state.err != nil {
+			return ast.Terminate
+		}
+		return ast.GoToNext
+	}
+
+	r.state.include("code_block", r.palette.ReplaceIn(defaultCodeBlockCss), "")
+	fmt.Fprint(w, `<div class="code_block">`)
 	r.state.renderer.CodeBlock(w, n)
 	fmt.Fprintf(w, "</div>")
 	return ast.GoToNext
@@ -283,11 +526,14 @@ func (r *Renderer) processHTMLOpeningTag(
 
 	switch tag.Data {
 	case "img":
-		if !r.lazyImageLoading {
+		rewritten := r.rewriteTagRefs(tag.Data, tag.Attr)
+		if !r.lazyImageLoading && !rewritten {
 			break
 		}
 
-		setTagAttr(tag, "loading", "lazy")
+		if r.lazyImageLoading {
+			setTagAttr(tag, "loading", "lazy")
+		}
 		if v, err := renderTag(tag); err != nil {
 			r.state.err = err
 			return ast.Terminate
@@ -295,35 +541,35 @@ func (r *Renderer) processHTMLOpeningTag(
 			n.Literal = v
 		}
 
+	case "a", "audio", "source", "video":
+		n.Literal = r.rewriteHTMLRefs(n.Literal)
+
 	case "ins":
-		switch getTagAttr(tag, "type") {
-		case "book_bib":
-			r.state.htmlTagStack.push(
-				htmlClosingTagIns,
-				func(b *htmlTag) ast.WalkStatus {
-					// Content inside ins tag is ignored.
-					bookBibliography(
-						w,
-						r.palette,
-						getTagAttr(tag, "title"),
-						getTagAttr(tag, "cover"),
-						getTagAttr(tag, "link"),
-						getTagAttr(tag, "author"),
-					)
-					return ast.GoToNext
-				},
-			)
+		t := r.tags[getTagAttr(tag, "type")]
+		if t == nil {
+			break
+		}
 
-			return ast.GoToNext
+		if bytes.HasSuffix(n.Literal, htmlSelfClosingTagSuffix) {
+			return r.renderTag(w, tag, t, nil)
 		}
 
+		r.state.htmlTagStack.push(
+			htmlClosingTagIns,
+			func(b *htmlTag) ast.WalkStatus {
+				return r.renderTag(w, tag, t, b.buf.Bytes())
+			},
+		)
+
+		return ast.GoToNext
+
 	case "mark":
 		kind, val := getTagOnlyAttr(tag)
 		if kind == "" {
 			break
 		}
 
-		if color, found := r.colorMap[kind]; !found {
+		if _, found := r.colorMap[kind]; !found {
 			break
 		} else {
 			r.state.htmlTagStack.push(
@@ -331,18 +577,20 @@ func (r *Renderer) processHTMLOpeningTag(
 				func(b *htmlTag) ast.WalkStatus {
 					content := b.buf.String()
 					id := ""
-					if keyword.ValidType(kind) {
-						id = hash20([]byte(content))
+					if r.kwTypes[keyword.Type(kind)] {
+						// Repeated keywords share the same hash, make
+						// the element ID unique per occurrence.
+						id = r.state.slugger.unique(hash20([]byte(content)))
 						r.state.kws.add(keyword.Type(kind), content, id)
 					}
 
-					switch val {
-					case "baike", "baidu":
-						content = link(content, baiduBaike(content))
-					case "wikicn":
-						content = link(content, wikipediaCn(content))
+					if tmpl := r.linkProviders[val]; tmpl != "" {
+						r.state.include("hl_link", highlightLinkCss, "")
+						content = link(content, lookupURL(tmpl, lookupTerm(content)))
 					}
-					highlight(w, id, content, color)
+					bg, fg := r.colorCss(kind)
+					r.state.include("hl_"+kind, highlightCss(kind, bg, fg), "")
+					highlight(w, id, kind, content)
 
 					return ast.GoToNext
 				},
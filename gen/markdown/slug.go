@@ -0,0 +1,65 @@
+package markdown
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultSlug = "section"
+)
+
+// Slugify turns text into an ID or path element. Letters and digits of any
+// script are kept, so CJK text produces readable slugs. Runs of spaces,
+// hyphens and underscores become a single hyphen and everything else is
+// dropped. The slug of text without letters or digits is empty.
+func Slugify(text string) string {
+	var b strings.Builder
+	sep := false
+	for _, c := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			if sep && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			sep = false
+			b.WriteRune(c)
+		case unicode.IsSpace(c) || c == '-' || c == '_':
+			sep = true
+		}
+	}
+	return b.String()
+}
+
+// slugify turns heading text into an element ID, see Slugify, falling back
+// to defaultSlug for headings without letters or digits.
+func slugify(text string) string {
+	if slug := Slugify(text); slug != "" {
+		return slug
+	}
+	return defaultSlug
+}
+
+// slugger hands out unique IDs within a document.
+type slugger struct {
+	seen map[string]bool
+}
+
+func newSlugger() *slugger {
+	return &slugger{
+		seen: map[string]bool{},
+	}
+}
+
+// unique returns id, or id suffixed with the lowest free counter if it is
+// already taken.
+func (s *slugger) unique(id string) string {
+	res := id
+	for i := 1; s.seen[res]; i++ {
+		res = fmt.Sprintf("%s-%d", id, i)
+	}
+	s.seen[res] = true
+
+	return res
+}
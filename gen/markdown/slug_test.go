@@ -0,0 +1,26 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugify(t *testing.T) {
+	require.Equal(t, "hello-world", slugify("Hello, World!"))
+	require.Equal(t, "using-genhtml", slugify("Using gen.Html"))
+	require.Equal(t, "安装-go-119", slugify("安装 Go 1.19"))
+	require.Equal(t, "a-b", slugify("  a -- b  "))
+	require.Equal(t, "section", slugify("!!!"))
+	require.Equal(t, "", Slugify("!!!"))
+	require.Equal(t, "static-sites", Slugify(" Static  Sites "))
+}
+
+func TestSlugger(t *testing.T) {
+	s := newSlugger()
+	require.Equal(t, "intro-1", s.unique("intro-1"))
+	require.Equal(t, "intro", s.unique("intro"))
+	require.Equal(t, "intro-2", s.unique("intro"))
+	require.Equal(t, "intro-3", s.unique("intro"))
+	require.Equal(t, "other", s.unique("other"))
+}
@@ -0,0 +1,142 @@
+package markdown
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+
+	"github.com/gomarkdown/markdown/ast"
+	"golang.org/x/net/html"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+// TagContext is the input of a TagHandler.
+type TagContext struct {
+	// Attrs holds all attributes of the opening tag, including type.
+	Attrs map[string]string
+	// Content is the rendered content between the opening and the closing
+	// tag. It is empty for a self-closing tag.
+	Content template.HTML
+	Palette color.Palette
+	// AssetRef records a reference to an asset, e.g., an image, so that
+	// it is published with the page, and returns the URL to refer to it.
+	AssetRef func(ref string) string
+}
+
+// TagOutput is the result of a TagHandler. Css and Js are added to the
+// document once, no matter how many times the tag is used.
+type TagOutput struct {
+	Html template.HTML
+	Css  template.CSS
+	Js   template.JS
+}
+
+// TagHandler renders a custom tag. A nil output renders nothing.
+type TagHandler func(ctx *TagContext) (*TagOutput, error)
+
+type tagEntry struct {
+	block   bool
+	handler TagHandler
+}
+
+// RegisterTag registers a handler for <ins type="name">...</ins> and the
+// self-closing <ins type="name"/> tags. Block handlers render block elements
+// and are not wrapped by a paragraph when the tag is the only content of it.
+// Registering an existing name replaces its handler, including the built-in
+// ones.
+func (r *Renderer) RegisterTag(name string, block bool, h TagHandler) {
+	r.tags[name] = &tagEntry{
+		block:   block,
+		handler: h,
+	}
+}
+
+func (r *Renderer) registerBuiltinTags() {
+	r.RegisterTag("toc", true, tocTag)
+	// The bibliography keeps its paragraph wrapper for compatibility with
+	// existing pages.
+	r.RegisterTag("book_bib", false, bookBibTag)
+	for kind, _ := range admonitionKinds {
+		r.RegisterTag(kind, true, admonitionTag(kind))
+	}
+}
+
+func (r *Renderer) renderTag(
+	w io.Writer,
+	tag *html.Node,
+	t *tagEntry,
+	content []byte,
+) ast.WalkStatus {
+	attrs := map[string]string{}
+	for _, a := range tag.Attr {
+		attrs[a.Key] = a.Val
+	}
+
+	out, err := t.handler(&TagContext{
+		Attrs:   attrs,
+		Content: template.HTML(content),
+		Palette: r.palette,
+		AssetRef: func(ref string) string {
+			if ref == "" || isExternalLink(ref) {
+				return ref
+			}
+			r.state.internalRefs = append(r.state.internalRefs, ref)
+			return r.rewriteRef(ref)
+		},
+	})
+	if err != nil {
+		r.state.err = err
+		return ast.Terminate
+	}
+	if out == nil {
+		return ast.GoToNext
+	}
+
+	r.state.include(
+		string(out.Css)+string(out.Js),
+		string(out.Css),
+		string(out.Js),
+	)
+	io.WriteString(w, string(out.Html))
+
+	return ast.GoToNext
+}
+
+func tocTag(ctx *TagContext) (*TagOutput, error) {
+	// Content inside ins tag is ignored.
+	return &TagOutput{
+		Html: ToCPlaceholder,
+	}, nil
+}
+
+func bookBibTag(ctx *TagContext) (*TagOutput, error) {
+	// Content inside ins tag is ignored.
+	var b bytes.Buffer
+	bookBibliography(
+		&b,
+		ctx.Attrs["title"],
+		ctx.AssetRef(ctx.Attrs["cover"]),
+		ctx.Attrs["link"],
+		ctx.Attrs["author"],
+	)
+
+	return &TagOutput{
+		Html: template.HTML(b.String()),
+		Css:  template.CSS(bookBibCss(ctx.Palette)),
+	}, nil
+}
+
+func admonitionTag(kind string) TagHandler {
+	return func(ctx *TagContext) (*TagOutput, error) {
+		var b bytes.Buffer
+		admonitionOpen(&b, kind, ctx.Attrs["title"])
+		b.WriteString(string(ctx.Content))
+		admonitionClose(&b)
+
+		return &TagOutput{
+			Html: template.HTML(b.String()),
+			Css:  template.CSS(admonitionCss(ctx.Palette)),
+		}, nil
+	}
+}
@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+func TestRegisterTag(t *testing.T) {
+	r := NewRenderer(color.DefaultPalette, ".html", false)
+	r.RegisterTag("badge", false, func(ctx *TagContext) (*TagOutput, error) {
+		return &TagOutput{
+			Html: template.HTML(fmt.Sprintf(
+				`<span class="badge" style="background:%s">%s %s</span>`,
+				ctx.Palette.Green,
+				ctx.Attrs["version"],
+				ctx.Content,
+			)),
+			Css: ".badge{}",
+		}, nil
+	})
+
+	doc, err := r.Render(NewParser().Parse([]byte(
+		`Since <ins type="badge" version="v1.2">*new*</ins> and <ins type="badge" version="v2"/>.`,
+	)))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"<p>Since <span class=\"badge\" style=\"background:#00FF00\">v1.2 <em>new</em></span> and <span class=\"badge\" style=\"background:#00FF00\">v2 </span>.</p>\n",
+		string(doc.Html),
+	)
+	require.Equal(t, 1, strings.Count(string(doc.Css), ".badge{}"))
+
+	errFailed := errors.New("failed")
+	r.RegisterTag("broken", true, func(ctx *TagContext) (*TagOutput, error) {
+		return nil, errFailed
+	})
+	_, err = r.Render(NewParser().Parse([]byte(`<ins type="broken"/>`)))
+	require.ErrorIs(t, err, errFailed)
+
+	r.RegisterTag("empty", false, func(ctx *TagContext) (*TagOutput, error) {
+		return nil, nil
+	})
+	doc, err = r.Render(NewParser().Parse([]byte(`A <ins type="empty">x</ins>B`)))
+	require.NoError(t, err)
+	require.Equal(t, "<p>A B</p>\n", string(doc.Html))
+}
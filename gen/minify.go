@@ -0,0 +1,357 @@
+package gen
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// minifyCss removes comments and whitespace that has no meaning from css.
+// Quoted strings are kept as is.
+func minifyCss(css string) string {
+	var out []byte
+	last := func() byte {
+		if len(out) == 0 {
+			return 0
+		}
+		return out[len(out)-1]
+	}
+
+	space := false
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				return string(out)
+			}
+			i += end + 3
+
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = len(out) > 0
+
+		default:
+			if space && !strings.ContainsRune("{};,>", rune(c)) &&
+				!strings.ContainsRune("{};,>:", rune(last())) {
+				out = append(out, ' ')
+			}
+			space = false
+
+			if c == '"' || c == '\'' {
+				end := i + 1
+				for end < len(css) && css[end] != c {
+					if css[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end >= len(css) {
+					end = len(css) - 1
+				}
+				out = append(out, css[i:end+1]...)
+				i = end
+				continue
+			}
+
+			// The last declaration of a block needs no semicolon.
+			if c == '}' && last() == ';' {
+				out = out[:len(out)-1]
+			}
+			out = append(out, c)
+		}
+	}
+
+	return string(out)
+}
+
+// minifyJs removes indentation, blank lines and full line comments from js.
+// Line breaks are kept, as statements may rely on them. Lines starting or
+// ending within a template literal keep their whitespace, which is part of
+// the string.
+func minifyJs(js string) string {
+	var lines []string
+	var s jsScanner
+	for _, l := range strings.Split(js, "\n") {
+		inLiteral := s.inTemplate()
+		s.scanLine(l)
+
+		if !inLiteral {
+			l = strings.TrimLeft(l, " \t\r")
+			if l == "" || strings.HasPrefix(l, "//") {
+				continue
+			}
+		}
+		if !s.inTemplate() {
+			l = strings.TrimRight(l, " \t\r")
+		}
+		lines = append(lines, l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// jsScanner tracks whether js is within a template literal, line by line.
+// It knows strings, comments and substitutions, e.g., `a ${b} c`, which is
+// enough for the scripts of pages, though not for regular expressions
+// containing quotes.
+type jsScanner struct {
+	// stack holds -1 for a template literal and the brace depth of code in
+	// a substitution.
+	stack   []int
+	comment bool
+}
+
+func (s *jsScanner) inTemplate() bool {
+	return len(s.stack) > 0 && s.stack[len(s.stack)-1] < 0
+}
+
+func (s *jsScanner) scanLine(l string) {
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case s.comment:
+			if c == '*' && i+1 < len(l) && l[i+1] == '/' {
+				s.comment = false
+				i++
+			}
+
+		case s.inTemplate():
+			switch {
+			case c == '\\':
+				i++
+			case c == '`':
+				s.stack = s.stack[:len(s.stack)-1]
+			case c == '$' && i+1 < len(l) && l[i+1] == '{':
+				s.stack = append(s.stack, 0)
+				i++
+			}
+
+		case c == '/' && i+1 < len(l) && l[i+1] == '/':
+			return
+
+		case c == '/' && i+1 < len(l) && l[i+1] == '*':
+			s.comment = true
+			i++
+
+		case c == '"' || c == '\'':
+			for i++; i < len(l) && l[i] != c; i++ {
+				if l[i] == '\\' {
+					i++
+				}
+			}
+
+		case c == '`':
+			s.stack = append(s.stack, -1)
+
+		case c == '{' && len(s.stack) > 0:
+			s.stack[len(s.stack)-1]++
+
+		case c == '}' && len(s.stack) > 0:
+			if s.stack[len(s.stack)-1] == 0 {
+				// The end of a substitution.
+				s.stack = s.stack[:len(s.stack)-1]
+			} else {
+				s.stack[len(s.stack)-1]--
+			}
+		}
+	}
+}
+
+var (
+	// blockTags are elements whose surrounding whitespace is not rendered.
+	blockTags = map[string]bool{
+		"html": true, "head": true, "body": true, "title": true,
+		"meta": true, "link": true, "script": true, "style": true,
+		"div": true, "p": true, "ul": true, "ol": true, "li": true,
+		"dl": true, "dt": true, "dd": true, "table": true, "thead": true,
+		"tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+		"h6": true, "header": true, "footer": true, "nav": true,
+		"main": true, "section": true, "article": true, "aside": true,
+		"figure": true, "figcaption": true, "blockquote": true,
+		"pre": true, "hr": true, "br": true, "form": true,
+		"details": true, "summary": true,
+	}
+
+	// preservedTags are elements whose content is kept as is.
+	preservedTags = map[string]bool{
+		"pre":      true,
+		"code":     true,
+		"textarea": true,
+	}
+)
+
+type htmlToken struct {
+	t    html.TokenType
+	name string
+	raw  []byte
+}
+
+func (t *htmlToken) isBlock() bool {
+	return t != nil && t.t != html.TextToken && blockTags[t.name]
+}
+
+// minifyHtml collapses whitespace, removes comments and minifies the
+// embedded CSS and JS of a page. The content of pre, code and textarea
+// elements, and of scripts holding data, is left untouched.
+func minifyHtml(src []byte) []byte {
+	var tokens []*htmlToken
+	z := html.NewTokenizer(bytes.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt == html.CommentToken {
+			continue
+		}
+
+		t := &htmlToken{
+			t:   tt,
+			raw: append([]byte(nil), z.Raw()...),
+		}
+		if tt == html.StartTagToken || tt == html.EndTagToken ||
+			tt == html.SelfClosingTagToken {
+			name, _ := z.TagName()
+			t.name = string(name)
+		}
+		tokens = append(tokens, t)
+	}
+
+	var out bytes.Buffer
+	preserved := 0
+	raw := ""
+	for i, t := range tokens {
+		switch t.t {
+		case html.StartTagToken:
+			if preservedTags[t.name] {
+				preserved++
+			}
+			switch {
+			case t.name == "style":
+				raw = t.name
+			case t.name == "script" && isJsScript(t.raw):
+				raw = t.name
+			case t.name == "script":
+				// Data, e.g., JSON, is kept as is.
+				raw = "data"
+			}
+			out.Write(minifyTag(t.raw))
+
+		case html.EndTagToken:
+			if preservedTags[t.name] && preserved > 0 {
+				preserved--
+			}
+			raw = ""
+			out.Write(minifyTag(t.raw))
+
+		case html.SelfClosingTagToken:
+			out.Write(minifyTag(t.raw))
+
+		case html.TextToken:
+			switch {
+			case raw == "style":
+				out.WriteString(minifyCss(string(t.raw)))
+			case raw == "script":
+				out.WriteString(minifyJs(string(t.raw)))
+			case raw == "data" || preserved > 0:
+				out.Write(t.raw)
+			default:
+				var prev, next *htmlToken
+				if i > 0 {
+					prev = tokens[i-1]
+				}
+				if i < len(tokens)-1 {
+					next = tokens[i+1]
+				}
+
+				text := collapseSpace(t.raw)
+				if prev == nil || prev.isBlock() {
+					text = bytes.TrimLeft(text, " ")
+				}
+				if next == nil || next.isBlock() {
+					text = bytes.TrimRight(text, " ")
+				}
+				out.Write(text)
+			}
+
+		default:
+			out.Write(t.raw)
+		}
+	}
+
+	return out.Bytes()
+}
+
+// isJsScript checks if a script start tag holds JS, as opposed to data,
+// e.g., JSON.
+func isJsScript(tag []byte) bool {
+	z := html.NewTokenizer(bytes.NewReader(tag))
+	z.Next()
+	for {
+		k, v, more := z.TagAttr()
+		if string(k) == "type" {
+			t := strings.ToLower(string(v))
+			return t == "" || t == "module" || strings.Contains(t, "javascript")
+		}
+		if !more {
+			return true
+		}
+	}
+}
+
+// minifyTag collapses whitespace between the attributes of a tag. Attribute
+// names are kept as written, as SVG attributes are case sensitive.
+func minifyTag(tag []byte) []byte {
+	var out []byte
+	var quote byte
+	space := false
+	for _, c := range tag {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case isSpace(c):
+			space = true
+			continue
+		}
+
+		if space && c != '>' && c != '=' &&
+			out[len(out)-1] != '=' {
+			out = append(out, ' ')
+		}
+		space = false
+		out = append(out, c)
+	}
+
+	return out
+}
+
+func collapseSpace(text []byte) []byte {
+	var out []byte
+	space := false
+	for _, c := range text {
+		if isSpace(c) {
+			space = true
+			continue
+		}
+		if space {
+			out = append(out, ' ')
+			space = false
+		}
+		out = append(out, c)
+	}
+	if space {
+		out = append(out, ' ')
+	}
+
+	return out
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
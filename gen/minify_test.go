@@ -0,0 +1,90 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinifyCss(t *testing.T) {
+	require.Equal(
+		t,
+		`@media (min-width:1080px){.a b,.c>d{color:#000;content:"a  b"}}`,
+		minifyCss(`
+/* comment */
+@media (min-width: 1080px) {
+	.a b, .c > d {
+		color: #000;
+		content: "a  b";
+	}
+}
+`),
+	)
+}
+
+func TestMinifyJs(t *testing.T) {
+	require.Equal(
+		t,
+		"var a = 1;\nvar b = \"http://x\";",
+		minifyJs(`
+	// comment
+	var a = 1;
+
+	var b = "http://x";
+`),
+	)
+}
+
+func TestMinifyHtml(t *testing.T) {
+	require.Equal(
+		t,
+		`<!DOCTYPE html><html><head><style>.a{color:red}</style></head><body><div class="x"><p>a <b>b</b> <i>c</i> d</p><pre><code>x  =  1
+  y</code></pre><p>e <code>f  g</code></p><svg viewBox="0 0 1 1"></svg></div><script>var a = 1;</script></body></html>`,
+		string(minifyHtml([]byte(`<!DOCTYPE html>
+<html>
+<head>
+	<style>
+		.a {
+			color: red;
+		}
+	</style>
+</head>
+<body>
+	<!-- comment -->
+	<div   class="x">
+		<p>a   <b>b</b>
+		<i>c</i>  d</p>
+<pre><code>x  =  1
+  y</code></pre>
+		<p>e <code>f  g</code></p>
+		<svg viewBox="0 0 1 1"></svg>
+	</div>
+	<script>
+		var a = 1;
+	</script>
+</body>
+</html>
+`))),
+	)
+}
+
+func TestMinifyJsTemplateLiteral(t *testing.T) {
+	require.Equal(
+		t,
+		"var a = `x\n    y ${f({b: `\n  c`})}\n\n  z`;\nvar d = 1;",
+		minifyJs(`
+	var a = `+"`x\n    y ${f({b: `\n  c`})}\n\n  z`"+`;
+	// comment
+	var d = 1;
+`),
+	)
+}
+
+func TestMinifyHtmlDataScript(t *testing.T) {
+	data := `<script type="application/ld+json">
+	{
+		"name":  "a  b"
+	}
+</script>`
+	require.Equal(t, data, string(minifyHtml([]byte(data))))
+}
@@ -5,9 +5,37 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	defaultBannerCss = `
+.banner {
+	width: 100%;
+	height: {{ .BannerHeight }};
+	object-fit: cover;
+}
+`
+
+	defaultNavCss = `
+.nav_links {
+	margin-left: 1em;
+}
+`
+
+	defaultFooterCss = `
+.footer_note {
+	max-width: fit-content;
+	margin-inline: auto;
+	font-size: 0.8em;
+}
+`
 )
 
 var (
@@ -53,6 +81,16 @@ func newPageConfig(m map[string]interface{}) *pageConfig {
 	}
 }
 
+// setDefaults sets the keys missing in the page config to the values of
+// defaults.
+func (c *pageConfig) setDefaults(defaults map[string]interface{}) {
+	for k, v := range defaults {
+		if _, found := c.m[k]; !found {
+			c.m[k] = v
+		}
+	}
+}
+
 func (c *pageConfig) bannerRef() string {
 	if c.m["banner"] == nil {
 		return ""
@@ -65,6 +103,135 @@ func (c *pageConfig) bannerRef() string {
 	return ref
 }
 
+func (c *pageConfig) title() string {
+	if c.m["title"] == nil {
+		return ""
+	}
+
+	t, ok := c.m["title"].(string)
+	if !ok {
+		return ""
+	}
+	return t
+}
+
+// date returns the publishing date of the page, the zero time if unset or
+// invalid.
+func (c *pageConfig) date() time.Time {
+	return c.timeVal("date")
+}
+
+// lastMod returns the last modification time of the page, the zero time if
+// unset or invalid.
+func (c *pageConfig) lastMod() time.Time {
+	return c.timeVal("lastmod")
+}
+
+// tags returns the tags of the page, given as a list or a comma separated
+// string.
+func (c *pageConfig) tags() []string {
+	var tags []string
+	switch v := c.m["tags"].(type) {
+	case []interface{}:
+		for _, t := range v {
+			tags = append(tags, fmt.Sprint(t))
+		}
+	case string:
+		tags = strings.Split(v, ",")
+	}
+
+	var res []string
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+func (c *pageConfig) summary() string {
+	return c.stringVal("summary", "")
+}
+
+// changeFreq returns the sitemap change frequency of the page, empty if
+// unset or invalid.
+func (c *pageConfig) changeFreq() string {
+	v := c.stringVal("changefreq", "")
+	if !validChangeFreqs[v] {
+		return ""
+	}
+	return v
+}
+
+// priority returns the sitemap priority of the page in [0, 1], empty if
+// unset or invalid.
+func (c *pageConfig) priority() string {
+	var v float64
+	switch p := c.m["priority"].(type) {
+	case int:
+		v = float64(p)
+	case float64:
+		v = p
+	default:
+		return ""
+	}
+	if v < 0 || v > 1 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// noIndex checks if the page is kept out of the sitemap.
+func (c *pageConfig) noIndex() bool {
+	return c.boolVal("noindex", false)
+}
+
+func (c *pageConfig) weight() int {
+	return c.intVal("weight", 0)
+}
+
+func (c *pageConfig) prevNext(def bool) bool {
+	return c.boolVal("prev_next", def)
+}
+
+func (c *pageConfig) lightbox(def bool) bool {
+	return c.boolVal("lightbox", def)
+}
+
+func (c *pageConfig) tocConfig(def ToCConfig) ToCConfig {
+	return ToCConfig{
+		MinLevel:  c.intVal("toc_min_level", def.MinLevel),
+		MaxLevel:  c.intVal("toc_max_level", def.MaxLevel),
+		Numbering: c.boolVal("toc_numbering", def.Numbering),
+		Expanded:  c.boolVal("toc_expanded", def.Expanded),
+		ScrollSpy: c.boolVal("toc_scroll_spy", def.ScrollSpy),
+	}
+}
+
+func (c *pageConfig) kwsSort() string {
+	return c.stringVal("kws_sort", "")
+}
+
+// math returns the math rendering mode of the page, or an empty mode if the
+// page does not enable math.
+func (c *pageConfig) math(def markdown.MathMode) (markdown.MathMode, error) {
+	switch v := c.m["math"].(type) {
+	case nil:
+		return "", nil
+	case bool:
+		if v {
+			return def, nil
+		}
+		return "", nil
+	case string:
+		if markdown.ValidMathMode(markdown.MathMode(v)) {
+			return markdown.MathMode(v), nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid math mode %q", fmt.Sprint(c.m["math"]))
+}
+
 func (c *pageConfig) leftPane() string {
 	if c.m["left_pane"] == nil {
 		return ""
@@ -89,20 +256,86 @@ func (c *pageConfig) rightPane() string {
 	return t
 }
 
-func (c *pageConfig) header() *HtmlComponent {
+func (c *pageConfig) stringVal(key string, def string) string {
+	if c.m[key] == nil {
+		return def
+	}
+
+	v, ok := c.m[key].(string)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+func (c *pageConfig) intVal(key string, def int) int {
+	if c.m[key] == nil {
+		return def
+	}
+
+	v, ok := c.m[key].(int)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// timeVal parses a time given as "2006-01-02", "2006-01-02 15:04" or
+// RFC 3339, YAML parses some of them into time.Time.
+func (c *pageConfig) timeVal(key string) time.Time {
+	switch v := c.m[key].(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range []string{
+			"2006-01-02",
+			"2006-01-02 15:04",
+			time.RFC3339,
+		} {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+func (c *pageConfig) boolVal(key string, def bool) bool {
+	if c.m[key] == nil {
+		return def
+	}
+
+	v, ok := c.m[key].(bool)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+func (c *pageConfig) header(rewrite markdown.RefRewriter) *HtmlComponent {
 	if c.m["banner"] == nil {
 		return &HtmlComponent{
 			Html: template.HTML(""),
 		}
 	}
 
-	return &HtmlComponent{
-		Html: template.HTML(fmt.Sprintf(
-			`<img src="%v" style="width:100%%;height:%s;object-fit:cover;">`,
-			c.m["banner"],
-			imgBannerHeight,
-		)),
+	banner := fmt.Sprint(c.m["banner"])
+	if ref := c.bannerRef(); ref != "" {
+		banner = rewrite(ref)
 	}
+
+	hc := &HtmlComponent{
+		Html: template.HTML(fmt.Sprintf(`<img class="banner" src="%v">`, banner)),
+	}
+	hc.include(template.CSS(strings.Replace(
+		defaultBannerCss,
+		"{{ .BannerHeight }}",
+		imgBannerHeight,
+		-1,
+	)), "")
+
+	return hc
 }
 
 func (c *pageConfig) nav() *HtmlComponent {
@@ -138,23 +371,27 @@ func (c *pageConfig) nav() *HtmlComponent {
 		links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, kv[1], kv[0]))
 	}
 
-	return &HtmlComponent{
+	hc := &HtmlComponent{
 		Html: template.HTML(fmt.Sprintf(
-			`<span>%s</span><span style="margin-left:1em;">%s</span>`,
+			`<span>%s</span><span class="nav_links">%s</span>`,
 			"\U0001F517",
 			strings.Join(links, " | "),
 		)),
 	}
+	hc.include(defaultNavCss, "")
+
+	return hc
 }
 
 func (c *pageConfig) footer() *HtmlComponent {
-	return &HtmlComponent{
+	hc := &HtmlComponent{
 		Html: template.HTML(`
-	<div style="max-width:fit-content;margin-inline:auto;">
-		<span style="font-size: 0.8em;">Generated from markdown by
+	<div class="footer_note">
+		Generated from markdown by
 		<a href="https://github.com/iamjinlei/proteus">proteus</a>
-		</span>
-		</div>
 	</div>`),
 	}
+	hc.include(defaultFooterCss, "")
+
+	return hc
 }
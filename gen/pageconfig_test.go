@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
 )
 
 func TestExtractPageConfig(t *testing.T) {
@@ -21,3 +23,31 @@ func TestExtractPageConfig(t *testing.T) {
 	_, _, err = extractPageConfig([]byte("<!---\ntest config\n"))
 	require.ErrorIs(t, err, ErrBrokenCommentTag)
 }
+
+func TestPageConfigMath(t *testing.T) {
+	for src, want := range map[string]markdown.MathMode{
+		"title: x":     "",
+		"math: false":  "",
+		"math: true":   markdown.MathMathML,
+		"math: katex":  markdown.MathKaTeX,
+		"math: mathml": markdown.MathMathML,
+	} {
+		cfg, _, err := extractPageConfig([]byte("<!---\n" + src + "\n--->\n"))
+		require.NoError(t, err)
+		mode, err := cfg.math(markdown.MathMathML)
+		require.NoError(t, err, src)
+		require.Equal(t, want, mode, src)
+	}
+
+	for _, src := range []string{"math: katx", "math: yes", "math: 1"} {
+		cfg, _, err := extractPageConfig([]byte("<!---\n" + src + "\n--->\n"))
+		require.NoError(t, err)
+		_, err = cfg.math(markdown.MathMathML)
+		require.Error(t, err, src)
+	}
+
+	h, err := NewHtml(DefaultConfig("", ".html"))
+	require.NoError(t, err)
+	_, err = h.Gen("/a.md.html", []byte("<!---\nmath: katx\n--->\n$x$"))
+	require.ErrorContains(t, err, `invalid math mode "katx"`)
+}
@@ -3,17 +3,11 @@ package gen
 var (
 	imgBannerHeight = "10em"
 
-	defaultLayout = `
-<!DOCTYPE html>
-<html>
-<head>
-{{ if .CanonicalDomain }}
-<link rel="canonical" href="{{ .CanonicalDomain }}/{{ .RelPath }}"/>
-{{ end }}
-<meta content="text/html;charset=utf-8" http-equiv="Content-Type">
-<meta content="utf-8" http-equiv="encoding">
-<title></title>
-<style>
+	defaultLayoutCss = `
+body {
+	color: {{ .Palette.Foreground }};
+	background-color: {{ .Palette.Background }};
+}
 @media (min-width: 1080px) {
 	.row {
 		display: grid;
@@ -53,24 +47,36 @@ var (
 	}
 	.row.footer {
 	}
-	{{ .Content.Header.Css }}
-	{{ .Content.Nav.Css }}
-	{{ .Content.MainLeft.Css }}
-	{{ .Content.MainRight.Css }}
-	{{ .Content.Main.Css }}
-	{{ .Content.Footer.Css }}
+`
+
+	defaultLayout = `
+<!DOCTYPE html>
+<html>
+<head>
+{{ if .CanonicalDomain }}
+<link rel="canonical" href="{{ .CanonicalDomain }}/{{ .RelPath }}"/>
+{{ end }}
+<meta content="text/html;charset=utf-8" http-equiv="Content-Type">
+<meta content="utf-8" http-equiv="encoding">
+<title></title>
+{{ if .Css }}
+<style>
+{{ .Css }}
 </style>
+{{ end }}
+{{ range .Stylesheets }}
+<link rel="stylesheet" href="{{ . }}">
+{{ end }}
+{{ range .Scripts }}
+<script defer src="{{ . }}"></script>
+{{ end }}
+{{ if .HeadJs }}
+<script>
+{{ .HeadJs }}
+</script>
+{{ end }}
 </head>
 <body>
-	<script>
-		{{ .Content.Header.Js }}
-		{{ .Content.Nav.Js }}
-		{{ .Content.MainLeft.Js }}
-		{{ .Content.MainRight.Js }}
-		{{ .Content.Main.Js }}
-		{{ .Content.Footer.Js }}
-	</script>
-
 	{{ if .Content.Header.Html }}
 	<div class="row header nonempty">
 		<div class="col-left">
@@ -122,6 +128,12 @@ var (
 		<div class="col-right">
 		</div>
 	</div>
+
+	{{ if .Js }}
+	<script>
+		{{ .Js }}
+	</script>
+	{{ end }}
 </body>
 </html>
 `
@@ -0,0 +1,127 @@
+package gen
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	defaultPageNavCss = `
+.pagenav {
+	display: grid;
+	grid-template-columns: 1fr 1fr;
+	margin-top: 3em;
+	padding-top: 1em;
+	border-top: 1px solid {{ .Palette.LightGray }};
+}
+.pagenav a {
+	text-decoration: none;
+	color: {{ .Palette.Foreground }};
+}
+.pagenav .next {
+	text-align: right;
+}
+.pagenav .label {
+	display: block;
+	font-size: 0.8em;
+	color: {{ .Palette.DarkGray }};
+}
+`
+)
+
+// pageSequence returns pages in reading order. An explicit order lists page
+// paths relative to the site root, unknown paths are ignored. Without an
+// explicit order, the site tree order is used.
+func (t *siteTree) pageSequence(order []string) []*PageMeta {
+	var seq []*PageMeta
+	if len(order) > 0 {
+		for _, relPath := range order {
+			if p := t.get(relPath); p != nil {
+				seq = append(seq, p)
+			}
+		}
+		return seq
+	}
+
+	var walk func(n *siteNode)
+	walk = func(n *siteNode) {
+		if n.page != nil {
+			seq = append(seq, n.page)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(t.tree())
+
+	return seq
+}
+
+func (t *siteTree) neighbors(
+	relPath string,
+	order []string,
+) (*PageMeta, *PageMeta) {
+	seq := t.pageSequence(order)
+	for i, p := range seq {
+		if p.RelPath != relPath {
+			continue
+		}
+
+		var prev, next *PageMeta
+		if i > 0 {
+			prev = seq[i-1]
+		}
+		if i < len(seq)-1 {
+			next = seq[i+1]
+		}
+		return prev, next
+	}
+
+	return nil, nil
+}
+
+func renderPageNav(
+	prev *PageMeta,
+	next *PageMeta,
+	palette color.Palette,
+) *HtmlComponent {
+	if prev == nil && next == nil {
+		return &HtmlComponent{}
+	}
+
+	prevHtml, nextHtml := "<span></span>", "<span></span>"
+	if prev != nil {
+		prevHtml = fmt.Sprintf(
+			`<a class="prev" href="%s"><span class="label">&larr; Previous</span>%s</a>`,
+			prev.RelPath,
+			template.HTMLEscapeString(prev.Title),
+		)
+	}
+	if next != nil {
+		nextHtml = fmt.Sprintf(
+			`<a class="next" href="%s"><span class="label">Next &rarr;</span>%s</a>`,
+			next.RelPath,
+			template.HTMLEscapeString(next.Title),
+		)
+	}
+
+	c := &HtmlComponent{
+		Html: template.HTML(fmt.Sprintf(
+			`<div class="pagenav">%s%s</div>`,
+			prevHtml,
+			nextHtml,
+		)),
+	}
+	c.add(pageNavChunk(palette))
+
+	return c
+}
+
+func pageNavChunk(palette color.Palette) *markdown.Include {
+	return &markdown.Include{
+		Css: template.CSS(palette.ReplaceIn(defaultPageNavCss)),
+	}
+}
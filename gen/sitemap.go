@@ -1,58 +1,196 @@
 package gen
 
 import (
-	"bytes"
 	"encoding/xml"
+	"fmt"
 	"net/url"
 	"sort"
 	"time"
 )
 
+const (
+	sitemapXmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	// SitemapPath is the path of the sitemap, or of the sitemap index when
+	// the URLs are split into several sitemaps at sitemapPartPath.
+	SitemapPath     = "/sitemap.xml"
+	sitemapPartPath = "/sitemap-%d.xml"
+	// sitemapMaxUrls is the max number of URLs of a sitemap by the sitemap
+	// protocol.
+	sitemapMaxUrls = 50000
+)
+
+var (
+	validChangeFreqs = map[string]bool{
+		"always":  true,
+		"hourly":  true,
+		"daily":   true,
+		"weekly":  true,
+		"monthly": true,
+		"yearly":  true,
+		"never":   true,
+	}
+)
+
 type SitemapURL struct {
-	Loc     string    `xml:"loc"`
-	LastMod time.Time `xml:"lastmod"`
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
 }
 
 type UrlSet struct {
-	Xmlns string        `xml:"xmlns,attr"`
-	Urls  []*SitemapURL `xml:"url"`
+	XMLName xml.Name      `xml:"urlset"`
+	Xmlns   string        `xml:"xmlns,attr"`
+	Urls    []*SitemapURL `xml:"url"`
+}
+
+type SitemapIndex struct {
+	XMLName  xml.Name        `xml:"sitemapindex"`
+	Xmlns    string          `xml:"xmlns,attr"`
+	Sitemaps []*SitemapEntry `xml:"sitemap"`
+}
+
+type SitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
 }
 
 type Sitemap struct {
 	domain string
-	rels   []string
-	UrlSet UrlSet `xml:"urlset"`
+	urls   map[string]*sitemapURL
+	// maxUrls is the max number of URLs per sitemap.
+	maxUrls int
+}
+
+type sitemapURL struct {
+	rel        string
+	lastMod    time.Time
+	changeFreq string
+	priority   string
 }
 
 func NewSitemap(domain string) *Sitemap {
 	return &Sitemap{
-		domain: normalizeDomain(domain),
-		UrlSet: UrlSet{
-			Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
-		},
+		domain:  normalizeDomain(domain),
+		urls:    map[string]*sitemapURL{},
+		maxUrls: sitemapMaxUrls,
 	}
 }
 
+// Add adds a page without a known last modification time.
 func (m *Sitemap) Add(rel string) {
-	m.rels = append(m.rels, normalizeRelPath(rel))
+	m.AddPage(&PageMeta{RelPath: rel})
+}
+
+// AddPage adds a page with its sitemap fields, unless the page is NoIndex.
+func (m *Sitemap) AddPage(p *PageMeta) {
+	if p.NoIndex {
+		return
+	}
+
+	rel := normalizeRelPath(p.RelPath)
+	m.urls[rel] = &sitemapURL{
+		rel:        rel,
+		lastMod:    p.LastMod,
+		changeFreq: p.ChangeFreq,
+		priority:   p.Priority,
+	}
 }
 
-func (m *Sitemap) Gen() ([]byte, error) {
-	now := time.Now()
-	sort.Strings(m.rels)
-	for _, rel := range m.rels {
-		loc, _ := url.JoinPath(m.domain, rel)
-		m.UrlSet.Urls = append(m.UrlSet.Urls, &SitemapURL{
-			Loc:     loc,
-			LastMod: now,
+// Gen generates the sitemap at SitemapPath. Above the URL limit of the
+// sitemap protocol, the URLs are split into sitemaps next to it, e.g.,
+// "/sitemap-1.xml", and a sitemap index is generated at SitemapPath.
+func (m *Sitemap) Gen() ([]*Asset, error) {
+	var urls []*sitemapURL
+	for _, u := range m.urls {
+		urls = append(urls, u)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].rel < urls[j].rel
+	})
+
+	if len(urls) <= m.maxUrls {
+		data, err := m.genUrlSet(urls)
+		if err != nil {
+			return nil, err
+		}
+		return []*Asset{{RelPath: SitemapPath, Data: data}}, nil
+	}
+
+	var files []*Asset
+	idx := &SitemapIndex{
+		Xmlns: sitemapXmlns,
+	}
+	for i := 0; i*m.maxUrls < len(urls); i++ {
+		end := (i + 1) * m.maxUrls
+		if end > len(urls) {
+			end = len(urls)
+		}
+		part := urls[i*m.maxUrls : end]
+
+		data, err := m.genUrlSet(part)
+		if err != nil {
+			return nil, err
+		}
+		rel := fmt.Sprintf(sitemapPartPath, i+1)
+		files = append(files, &Asset{RelPath: rel, Data: data})
+		idx.Sitemaps = append(idx.Sitemaps, &SitemapEntry{
+			Loc:     m.loc(rel),
+			LastMod: formatLastMod(latestLastMod(part)),
 		})
 	}
 
-	v, err := xml.Marshal(m.UrlSet)
+	data, err := marshalSitemap(idx)
 	if err != nil {
 		return nil, err
 	}
+	return append([]*Asset{{RelPath: SitemapPath, Data: data}}, files...), nil
+}
+
+func (m *Sitemap) genUrlSet(urls []*sitemapURL) ([]byte, error) {
+	set := &UrlSet{
+		Xmlns: sitemapXmlns,
+	}
+	for _, u := range urls {
+		set.Urls = append(set.Urls, &SitemapURL{
+			Loc:        m.loc(u.rel),
+			LastMod:    formatLastMod(u.lastMod),
+			ChangeFreq: u.changeFreq,
+			Priority:   u.priority,
+		})
+	}
+
+	return marshalSitemap(set)
+}
+
+func (m *Sitemap) loc(rel string) string {
+	loc, _ := url.JoinPath(m.domain, normalizeRelPath(rel))
+	return loc
+}
+
+func latestLastMod(urls []*sitemapURL) time.Time {
+	var t time.Time
+	for _, u := range urls {
+		if u.lastMod.After(t) {
+			t = u.lastMod
+		}
+	}
+	return t
+}
+
+// formatLastMod formats a time in the W3C datetime format, empty for the
+// zero time.
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
 
-	v = bytes.Replace(v, []byte("UrlSet"), []byte("urlset"), -1)
-	return append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`), v...), nil
+func marshalSitemap(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`), data...), nil
 }
@@ -1,8 +1,8 @@
 package gen
 
 import (
-	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -10,10 +10,88 @@ import (
 func TestSitemap(t *testing.T) {
 	m := NewSitemap("unnote.xyz")
 	m.Add("index.html")
-	m.Add("/foo/this.html")
+	m.AddPage(&PageMeta{
+		RelPath:    "/foo/this.html",
+		LastMod:    time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		ChangeFreq: "weekly",
+		Priority:   "0.8",
+	})
 	m.Add("/bar/that.html")
+	m.AddPage(&PageMeta{RelPath: "/draft.html", NoIndex: true})
 
-	xml, err := m.Gen()
+	files, err := m.Gen()
 	require.NoError(t, err)
-	fmt.Printf("%s\n", string(xml))
+	require.Len(t, files, 1)
+	require.Equal(t, SitemapPath, files[0].RelPath)
+	require.Equal(
+		t,
+		`<?xml version="1.0" encoding="UTF-8"?>`+
+			`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+			`<url><loc>https://unnote.xyz/bar/that.html</loc></url>`+
+			`<url><loc>https://unnote.xyz/foo/this.html</loc><lastmod>2024-05-06T07:08:09Z</lastmod><changefreq>weekly</changefreq><priority>0.8</priority></url>`+
+			`<url><loc>https://unnote.xyz/index.html</loc></url>`+
+			`</urlset>`,
+		string(files[0].Data),
+	)
+}
+
+func TestSitemapIndex(t *testing.T) {
+	m := NewSitemap("unnote.xyz")
+	m.maxUrls = 2
+	m.AddPage(&PageMeta{
+		RelPath: "/a.html",
+		LastMod: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	m.AddPage(&PageMeta{
+		RelPath: "/b.html",
+		LastMod: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	})
+	m.Add("/c.html")
+
+	files, err := m.Gen()
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	require.Equal(t, "/sitemap.xml", files[0].RelPath)
+	require.Equal(
+		t,
+		`<?xml version="1.0" encoding="UTF-8"?>`+
+			`<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`+
+			`<sitemap><loc>https://unnote.xyz/sitemap-1.xml</loc><lastmod>2024-02-01T00:00:00Z</lastmod></sitemap>`+
+			`<sitemap><loc>https://unnote.xyz/sitemap-2.xml</loc></sitemap>`+
+			`</sitemapindex>`,
+		string(files[0].Data),
+	)
+	require.Equal(t, "/sitemap-1.xml", files[1].RelPath)
+	require.Contains(t, string(files[1].Data), "/b.html")
+	require.Equal(t, "/sitemap-2.xml", files[2].RelPath)
+	require.Contains(t, string(files[2].Data), "/c.html")
+}
+
+func TestPageConfigSitemap(t *testing.T) {
+	h, err := NewHtml(DefaultConfig("", ".html"))
+	require.NoError(t, err)
+
+	p, err := h.Scan("/a.md.html", []byte(`<!---
+lastmod: 2024-05-06 07:08
+changefreq: monthly
+priority: 0.5
+noindex: true
+--->
+# A`))
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2024, 5, 6, 7, 8, 0, 0, time.UTC), p.LastMod)
+	require.Equal(t, "monthly", p.ChangeFreq)
+	require.Equal(t, "0.5", p.Priority)
+	require.True(t, p.NoIndex)
+
+	p, err = h.Scan("/b.md.html", []byte(`<!---
+changefreq: sometimes
+priority: 2
+--->
+# B`))
+	require.NoError(t, err)
+	require.True(t, p.LastMod.IsZero())
+	require.Empty(t, p.ChangeFreq)
+	require.Empty(t, p.Priority)
+	require.False(t, p.NoIndex)
 }
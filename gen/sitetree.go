@@ -0,0 +1,252 @@
+package gen
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	defaultSiteTreeCss = `
+.sitetree {
+	position: -webkit-sticky; /* Safari */
+	position: sticky;
+	float: left;
+	top: 10em;
+	margin-left: 2em;
+	font-size: 0.9em;
+}
+.sitetree ul {
+	list-style-type: none;
+	padding-left: 1em;
+	margin: 0.2em 0;
+}
+.sitetree li {
+	margin: 0.2em 0;
+}
+.sitetree summary {
+	cursor: pointer;
+}
+.sitetree a {
+	text-decoration: none;
+	color: {{ .Palette.Foreground }};
+}
+.sitetree .current > a {
+	font-weight: bold;
+	border-left: 3px solid {{ .Palette.DarkGray }};
+	padding-left: 0.4em;
+}
+`
+)
+
+// PageMeta describes a page discovered by the scan pass. RelPath is the
+// path of the generated html file relative to the site root.
+type PageMeta struct {
+	RelPath      string
+	Title        string
+	Weight       int
+	InternalRefs []string
+	// Date, Tags and Summary are set by page config, see Config.Blog. The
+	// summary defaults to the text of the first paragraph.
+	Date    time.Time
+	Tags    []string
+	Summary string
+	// LastMod, ChangeFreq and Priority are the sitemap fields set by the
+	// lastmod, changefreq and priority page configs. NoIndex pages are
+	// left out of the sitemap, see Sitemap.AddPage.
+	LastMod    time.Time
+	ChangeFreq string
+	Priority   string
+	NoIndex    bool
+}
+
+type siteNode struct {
+	name     string
+	page     *PageMeta
+	children []*siteNode
+}
+
+func (n *siteNode) title() string {
+	if n.page != nil {
+		return n.page.Title
+	}
+	return n.name
+}
+
+func (n *siteNode) weight() int {
+	if n.page != nil {
+		return n.page.Weight
+	}
+	return 0
+}
+
+func (n *siteNode) contains(relPath string) bool {
+	if n.page != nil && n.page.RelPath == relPath {
+		return true
+	}
+	for _, c := range n.children {
+		if c.contains(relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// siteTree organizes all scanned pages by their output directory. Within a
+// directory, pages come before sub-directories and are ordered by weight,
+// then title. Sub-directories are ordered by name.
+type siteTree struct {
+	pages map[string]*PageMeta
+	root  *siteNode
+}
+
+func newSiteTree() *siteTree {
+	return &siteTree{
+		pages: map[string]*PageMeta{},
+	}
+}
+
+func (t *siteTree) add(p *PageMeta) {
+	t.pages[p.RelPath] = p
+	// Invalidate the cached tree.
+	t.root = nil
+}
+
+func (t *siteTree) get(relPath string) *PageMeta {
+	return t.pages[relPath]
+}
+
+// all returns all pages, in no particular order.
+func (t *siteTree) all() []*PageMeta {
+	var pages []*PageMeta
+	for _, p := range t.pages {
+		pages = append(pages, p)
+	}
+	return pages
+}
+
+// tree returns the site tree, which must not be modified as it is shared by
+// all callers until the next add.
+func (t *siteTree) tree() *siteNode {
+	if t.root != nil {
+		return t.root
+	}
+
+	root := &siteNode{}
+	dirs := map[string]*siteNode{
+		"/": root,
+	}
+
+	var dirNode func(dir string) *siteNode
+	dirNode = func(dir string) *siteNode {
+		if n := dirs[dir]; n != nil {
+			return n
+		}
+
+		n := &siteNode{
+			name: filepath.Base(dir),
+		}
+		parent := dirNode(filepath.Dir(dir))
+		parent.children = append(parent.children, n)
+		dirs[dir] = n
+		return n
+	}
+
+	for _, p := range t.pages {
+		parent := dirNode(filepath.Dir(p.RelPath))
+		parent.children = append(parent.children, &siteNode{
+			name: filepath.Base(p.RelPath),
+			page: p,
+		})
+	}
+
+	sortSiteNode(root)
+	t.root = root
+
+	return root
+}
+
+func sortSiteNode(n *siteNode) {
+	sort.SliceStable(n.children, func(i, j int) bool {
+		a, b := n.children[i], n.children[j]
+		if (a.page == nil) != (b.page == nil) {
+			return a.page != nil
+		}
+		if a.weight() != b.weight() {
+			return a.weight() < b.weight()
+		}
+		if a.title() != b.title() {
+			return a.title() < b.title()
+		}
+		return a.name < b.name
+	})
+
+	for _, c := range n.children {
+		sortSiteNode(c)
+	}
+}
+
+func renderSiteTree(
+	t *siteTree,
+	relPath string,
+	palette color.Palette,
+) *HtmlComponent {
+	root := t.tree()
+	if len(root.children) == 0 {
+		return &HtmlComponent{}
+	}
+
+	c := &HtmlComponent{
+		Html: template.HTML(fmt.Sprintf(
+			`<div class="sitetree">%s</div>`,
+			renderSiteNodeList(root.children, relPath),
+		)),
+	}
+	c.add(siteTreeChunk(palette))
+
+	return c
+}
+
+func siteTreeChunk(palette color.Palette) *markdown.Include {
+	return &markdown.Include{
+		Css: template.CSS(palette.ReplaceIn(defaultSiteTreeCss)),
+	}
+}
+
+func renderSiteNodeList(ns []*siteNode, relPath string) string {
+	html := "<ul>"
+	for _, n := range ns {
+		if n.page != nil {
+			class := ""
+			if n.page.RelPath == relPath {
+				class = ` class="current"`
+			}
+			html += fmt.Sprintf(
+				`<li%s><a href="%s">%s</a></li>`,
+				class,
+				n.page.RelPath,
+				template.HTMLEscapeString(n.page.Title),
+			)
+			continue
+		}
+
+		open := ""
+		if n.contains(relPath) {
+			open = " open"
+		}
+		html += fmt.Sprintf(
+			`<li><details%s><summary>%s</summary>%s</details></li>`,
+			open,
+			template.HTMLEscapeString(n.name),
+			renderSiteNodeList(n.children, relPath),
+		)
+	}
+	html += "</ul>"
+
+	return html
+}
@@ -0,0 +1,49 @@
+package gen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteTree(t *testing.T) {
+	st := newSiteTree()
+	st.add(&PageMeta{RelPath: "/index.html", Title: "Home", Weight: -1})
+	st.add(&PageMeta{RelPath: "/b.md.html", Title: "B"})
+	st.add(&PageMeta{RelPath: "/a.md.html", Title: "A", Weight: 1})
+	st.add(&PageMeta{RelPath: "/guide/z.md.html", Title: "Z"})
+	st.add(&PageMeta{RelPath: "/guide/y.md.html", Title: "Y"})
+
+	root := st.tree()
+	require.Equal(t, 4, len(root.children))
+	require.Equal(t, "Home", root.children[0].title())
+	require.Equal(t, "B", root.children[1].title())
+	require.Equal(t, "A", root.children[2].title())
+	require.Equal(t, "guide", root.children[3].title())
+	require.Nil(t, root.children[3].page)
+	require.Equal(t, 2, len(root.children[3].children))
+	require.Equal(t, "Y", root.children[3].children[0].title())
+	require.Equal(t, "Z", root.children[3].children[1].title())
+	require.True(t, root.children[3].contains("/guide/z.md.html"))
+	require.False(t, root.children[3].contains("/a.md.html"))
+}
+
+func TestSiteTreeNeighbors(t *testing.T) {
+	st := newSiteTree()
+	st.add(&PageMeta{RelPath: "/index.html", Title: "Home", Weight: -1})
+	st.add(&PageMeta{RelPath: "/a.md.html", Title: "A"})
+	st.add(&PageMeta{RelPath: "/b.md.html", Title: "B"})
+
+	prev, next := st.neighbors("/a.md.html", nil)
+	require.Equal(t, "/index.html", prev.RelPath)
+	require.Equal(t, "/b.md.html", next.RelPath)
+
+	order := []string{"/b.md.html", "/unknown.md.html", "/a.md.html"}
+	prev, next = st.neighbors("/a.md.html", order)
+	require.Equal(t, "/b.md.html", prev.RelPath)
+	require.Nil(t, next)
+
+	prev, next = st.neighbors("/index.html", order)
+	require.Nil(t, prev)
+	require.Nil(t, next)
+}
@@ -0,0 +1,137 @@
+package gen
+
+import (
+	"html/template"
+	"path"
+	"strings"
+
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+const (
+	DefaultStaticPath = "/static"
+
+	staticCssName = "site.css"
+	staticJsName  = "site.js"
+)
+
+// staticBundle collects the CSS and JS chunks of all pages into shared
+// static files. Chunks are collected while pages are scanned, except for
+// inline chunks which only run on the pages requiring them. Once the
+// bundle is frozen, i.e., its file names are referred to by a generated
+// page, chunks it does not contain are inlined into pages instead.
+type staticBundle struct {
+	enabled     bool
+	path        string
+	fingerprint bool
+	frozen      bool
+	seen        map[markdown.Include]bool
+	css         []string
+	js          []string
+	// files are the bundle files, created once frozen.
+	files []*Asset
+}
+
+func newStaticBundle(enabled bool, staticPath string, fingerprint bool) *staticBundle {
+	if staticPath == "" {
+		staticPath = DefaultStaticPath
+	}
+
+	return &staticBundle{
+		enabled:     enabled,
+		path:        staticPath,
+		fingerprint: fingerprint,
+		seen:        map[markdown.Include]bool{},
+	}
+}
+
+// collect adds the chunks not yet in the bundle, unless it is frozen. Inline
+// chunks are never added, see markdown.Include.Inline.
+func (b *staticBundle) collect(chunks []*markdown.Include) {
+	if !b.enabled || b.frozen {
+		return
+	}
+
+	for _, c := range chunks {
+		if !c.Inline && !b.seen[*c] {
+			b.add(c)
+		}
+	}
+}
+
+func (b *staticBundle) add(i *markdown.Include) {
+	b.seen[*i] = true
+	if i.Css != "" {
+		b.css = append(b.css, string(i.Css))
+	}
+	if i.Js != "" {
+		b.js = append(b.js, string(i.Js))
+	}
+}
+
+func (b *staticBundle) freeze() {
+	if !b.enabled || b.frozen {
+		return
+	}
+	b.frozen = true
+
+	for _, f := range []struct {
+		name   string
+		chunks []string
+		sep    string
+		minify func(string) string
+	}{
+		{staticCssName, b.css, "\n", minifyCss},
+		// Chunks are separated by a semicolon, so that a chunk without a
+		// trailing semicolon is not continued by the next one.
+		{staticJsName, b.js, ";\n", minifyJs},
+	} {
+		if len(f.chunks) == 0 {
+			continue
+		}
+
+		data := []byte(f.minify(strings.Join(f.chunks, f.sep)))
+		relPath := path.Join(b.path, f.name)
+		if b.fingerprint {
+			relPath = fingerprintPath(relPath, data)
+		}
+		b.files = append(b.files, &Asset{
+			RelPath: relPath,
+			Data:    data,
+		})
+	}
+}
+
+// link collects the chunks of the layout and the components of a page. The
+// chunks in the bundle are linked, the rest is inlined.
+func (b *staticBundle) link(d *TemplateData, layout *markdown.Include) {
+	chunks := []*markdown.Include{layout}
+	for _, c := range d.Content.components() {
+		chunks = append(chunks, c.chunks()...)
+	}
+
+	b.collect(chunks)
+
+	var css, js strings.Builder
+	inlined := map[markdown.Include]bool{}
+	for _, c := range chunks {
+		if b.enabled && b.seen[*c] || inlined[*c] {
+			continue
+		}
+
+		inlined[*c] = true
+		css.WriteString(string(c.Css))
+		js.WriteString(string(c.Js))
+	}
+	d.Css = template.CSS(css.String())
+	d.Js = template.JS(js.String())
+
+	for _, f := range b.files {
+		switch path.Ext(f.RelPath) {
+		case ".css":
+			d.Stylesheets = append(d.Stylesheets, f.RelPath)
+		case ".js":
+			d.Scripts = append(d.Scripts, f.RelPath)
+		}
+	}
+}
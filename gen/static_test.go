@@ -0,0 +1,155 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedStatic(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	cfg.SharedStatic = true
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	src := []byte(`<!---
+right_pane: toc
+--->
+# A
+## B
+`)
+	_, err = h.Scan("/a.md.html", src)
+	require.NoError(t, err)
+
+	files := h.Static()
+	require.Len(t, files, 2)
+	require.Equal(t, "/static/site.css", files[0].RelPath)
+	require.Contains(t, string(files[0].Data), ".toc_tgl{")
+	require.Equal(t, "/static/site.js", files[1].RelPath)
+	require.Contains(t, string(files[1].Data), "toc_collapsed")
+
+	page, err := h.Gen("/a.md.html", src)
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Contains(t, html, `<link rel="stylesheet" href="/static/site.css">`)
+	require.Contains(t, html, `<script defer src="/static/site.js"></script>`)
+	require.NotContains(t, html, "<style>")
+	require.NotContains(t, html, "onclick")
+
+	// Pages not scanned before the files are frozen inline what is missing.
+	page, err = h.Gen("/b.md.html", []byte("`x`"))
+	require.NoError(t, err)
+	html = string(page.Html)
+	require.Contains(t, html, `<link rel="stylesheet" href="/static/site.css">`)
+	require.Contains(t, html, "<style>")
+	require.NotContains(t, html, ".toc_tgl")
+}
+
+func TestInlineStatic(t *testing.T) {
+	h, err := NewHtml(DefaultConfig("", ".html"))
+	require.NoError(t, err)
+
+	page, err := h.Gen("/a.md.html", []byte(`<!---
+right_pane: toc
+--->
+# A
+## B
+
+[[toc]]
+`))
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Empty(t, h.Static())
+	require.NotContains(t, html, `rel="stylesheet"`)
+	// The side and inline tables of contents share their CSS and JS.
+	require.Equal(t, 1, strings.Count(html, ".toc_tgl {"))
+	require.Equal(t, 1, strings.Count(html, "toc_collapsed\");"))
+}
+
+func TestSharedStaticInlineChunks(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	cfg.SharedStatic = true
+	cfg.ToC.ScrollSpy = true
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	math := []byte(`<!---
+math: katex
+right_pane: toc
+--->
+# A
+
+$x^2$
+
+` + "```mermaid\ngraph TD\n  A-->B\n```\n")
+	plain := []byte(`<!---
+right_pane: toc
+toc_scroll_spy: false
+--->
+# B
+## C
+`)
+	_, err = h.Scan("/math.md.html", math)
+	require.NoError(t, err)
+	_, err = h.Scan("/plain.md.html", plain)
+	require.NoError(t, err)
+
+	for _, f := range h.Static() {
+		require.NotContains(t, string(f.Data), "katex")
+		require.NotContains(t, string(f.Data), "mermaid")
+		require.NotContains(t, string(f.Data), "getBoundingClientRect")
+	}
+
+	page, err := h.Gen("/math.md.html", math)
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Contains(t, html, "katex.min.js")
+	require.Contains(t, html, "mermaid.esm.min.mjs")
+	require.Contains(t, html, "getBoundingClientRect")
+
+	page, err = h.Gen("/plain.md.html", plain)
+	require.NoError(t, err)
+	html = string(page.Html)
+	require.Contains(t, html, `<script defer src="/static/site.js"></script>`)
+	require.NotContains(t, html, "katex")
+	require.NotContains(t, html, "mermaid")
+	require.NotContains(t, html, "getBoundingClientRect")
+}
+
+func TestSharedStaticLightbox(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	cfg.SharedStatic = true
+	cfg.Lightbox = true
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	zoom := []byte("![a](a.png \"A\")\n")
+	plain := []byte(`<!---
+lightbox: false
+--->
+![b](b.png "B")
+`)
+	_, err = h.Scan("/zoom.md.html", zoom)
+	require.NoError(t, err)
+	_, err = h.Scan("/plain.md.html", plain)
+	require.NoError(t, err)
+
+	for _, f := range h.Static() {
+		require.NotContains(t, string(f.Data), "lightbox")
+		require.NotContains(t, string(f.Data), "zoom-in")
+	}
+
+	page, err := h.Gen("/zoom.md.html", zoom)
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Contains(t, html, "lightbox_caption")
+	require.Contains(t, html, "zoom-in")
+
+	page, err = h.Gen("/plain.md.html", plain)
+	require.NoError(t, err)
+	html = string(page.Html)
+	require.Contains(t, html, `<figure class="figure"`)
+	require.NotContains(t, html, "lightbox")
+	require.NotContains(t, html, "zoom-in")
+}
@@ -4,6 +4,7 @@ import (
 	"html/template"
 
 	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/markdown"
 )
 
 type Dimensions struct {
@@ -13,6 +14,58 @@ type HtmlComponent struct {
 	Html template.HTML
 	Css  template.CSS
 	Js   template.JS
+	// includes are the chunks making up Css and Js, see include.
+	includes []*markdown.Include
+}
+
+// include adds a chunk of CSS and JS to the component. Identical chunks
+// are only added once to a page, or to the shared static files.
+func (c *HtmlComponent) include(css template.CSS, js template.JS) {
+	c.add(&markdown.Include{
+		Css: css,
+		Js:  js,
+	})
+}
+
+// includeInline adds a chunk of CSS and JS that is kept in the pages
+// requiring it, see markdown.Include.Inline.
+func (c *HtmlComponent) includeInline(css template.CSS, js template.JS) {
+	c.add(&markdown.Include{
+		Css:    css,
+		Js:     js,
+		Inline: true,
+	})
+}
+
+// add adds a chunk of CSS and JS to the component.
+func (c *HtmlComponent) add(i *markdown.Include) {
+	c.Css += i.Css
+	c.Js += i.Js
+	c.includes = append(c.includes, i)
+}
+
+// merge adds the CSS and JS chunks of o to c.
+func (c *HtmlComponent) merge(o *HtmlComponent) {
+	for _, i := range o.chunks() {
+		c.add(i)
+	}
+}
+
+// chunks returns the CSS and JS chunks of the component. A component built
+// without include is a single chunk.
+func (c *HtmlComponent) chunks() []*markdown.Include {
+	if len(c.includes) > 0 {
+		return c.includes
+	}
+	if c.Css == "" && c.Js == "" {
+		return nil
+	}
+	return []*markdown.Include{
+		&markdown.Include{
+			Css: c.Css,
+			Js:  c.Js,
+		},
+	}
 }
 
 type Content struct {
@@ -24,17 +77,41 @@ type Content struct {
 	Footer    *HtmlComponent
 }
 
+// components returns all components in the order their CSS and JS are
+// added to the page.
+func (c *Content) components() []*HtmlComponent {
+	return []*HtmlComponent{
+		c.Header,
+		c.Nav,
+		c.MainLeft,
+		c.MainRight,
+		c.Main,
+		c.Footer,
+	}
+}
+
 type TemplateData struct {
 	CanonicalDomain string
 	RelPath         string
 	Palette         color.Palette
 	Dimensions      Dimensions
 	Content         Content
+	// Css and Js are the de-duplicated styles and scripts of the layout
+	// and all components, which are inlined into the page.
+	Css template.CSS
+	Js  template.JS
+	// Stylesheets and Scripts are the URLs of the shared static files, see
+	// Config.SharedStatic, and of the theme static files.
+	Stylesheets []string
+	Scripts     []string
+	// HeadJs is run before the page is rendered, see Config.ThemeToggle.
+	HeadJs template.JS
 }
 
 func newTemplateData(
 	domain string,
 	relPath string,
+	palette color.Palette,
 	header *HtmlComponent,
 	nav *HtmlComponent,
 	main *HtmlComponent,
@@ -45,7 +122,7 @@ func newTemplateData(
 	return &TemplateData{
 		CanonicalDomain: normalizeDomain(domain),
 		RelPath:         normalizeRelPath(relPath),
-		Palette:         color.DefaultPalette,
+		Palette:         palette,
 		Dimensions:      Dimensions{},
 		Content: Content{
 			Header:    header,
@@ -0,0 +1,283 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/iamjinlei/proteus/gen/color"
+)
+
+const (
+	themeLayoutFile = "layout.html"
+	themeConfigFile = "theme.yaml"
+	themeStaticDir  = "static"
+)
+
+const (
+	themeStorageKey = "proteus-theme"
+
+	defaultThemeToggleCss = `
+.theme_tgl {
+	position: fixed;
+	top: 1em;
+	right: 1em;
+	z-index: 50;
+	padding: 0.2em 0.5em;
+	font-size: 1.2em;
+	color: {{ .Palette.Foreground }};
+	background-color: {{ .Palette.LightGray }};
+	border: none;
+	border-radius: 4px;
+	cursor: pointer;
+}
+`
+
+	// The choice of the reader is stored as the data-theme attribute of the
+	// root element, which takes precedence over the system preference.
+	defaultThemeToggleJs = `
+document.addEventListener("click", function(e) {
+	if (!e.target.closest(".theme_tgl")) {
+		return;
+	}
+	var root = document.documentElement;
+	var theme = root.getAttribute("data-theme");
+	if (!theme) {
+		theme = matchMedia("(prefers-color-scheme: dark)").matches ? "dark" : "light";
+	}
+	theme = theme === "dark" ? "light" : "dark";
+	root.setAttribute("data-theme", theme);
+	try {
+		localStorage.setItem("` + themeStorageKey + `", theme);
+	} catch (err) {
+	}
+});
+`
+
+	// defaultThemeInitJs restores the choice of the reader before the page
+	// is rendered, to avoid flashing the other theme.
+	defaultThemeInitJs = `
+try {
+	var theme = localStorage.getItem("` + themeStorageKey + `");
+	if (theme) {
+		document.documentElement.setAttribute("data-theme", theme);
+	}
+} catch (err) {
+}
+`
+)
+
+// paletteCss declares the palette colors as CSS variables, see
+// color.Palette.ReplaceIn. With a dark palette, it is used when the system
+// prefers a dark color scheme, unless the reader picks a theme with the
+// toggle.
+func paletteCss(light color.Palette, dark *color.Palette) string {
+	css := fmt.Sprintf(":root {\n\tcolor-scheme: light;\n%s}\n", light.Vars())
+	if dark == nil {
+		return css
+	}
+
+	darkVars := fmt.Sprintf("\tcolor-scheme: dark;\n%s", dark.Vars())
+	return css + fmt.Sprintf(
+		"@media (prefers-color-scheme: dark) {\n:root:not([data-theme=\"light\"]) {\n%s}\n}\n"+
+			":root[data-theme=\"dark\"] {\n%s}\n",
+		darkVars,
+		darkVars,
+	)
+}
+
+// renderThemeToggle returns the button switching between the light and dark
+// palettes.
+func renderThemeToggle(palette color.Palette) *HtmlComponent {
+	c := &HtmlComponent{
+		Html: template.HTML(
+			`<button class="theme_tgl" title="Toggle dark mode" aria-label="Toggle dark mode">&#9680;</button>`,
+		),
+	}
+	c.include(
+		template.CSS(palette.ReplaceIn(defaultThemeToggleCss)),
+		template.JS(defaultThemeToggleJs),
+	)
+
+	return c
+}
+
+// PaletteConfig overrides palette colors by name, e.g., "light_gray", and
+// adds extra named colors. Colors are hex values.
+type PaletteConfig struct {
+	Colors map[string]string `yaml:"colors"`
+	Extra  map[string]string `yaml:"extra"`
+}
+
+// Apply returns p with the colors of the config, p itself if c is nil.
+func (c *PaletteConfig) Apply(p color.Palette) (color.Palette, error) {
+	if c == nil {
+		return p, nil
+	}
+	return p.Override(c.Colors, c.Extra)
+}
+
+type themeConfig struct {
+	Palette     *PaletteConfig `yaml:"palette"`
+	DarkPalette *PaletteConfig `yaml:"dark_palette"`
+	// Stylesheets and Scripts are paths of static files linked by every
+	// page, e.g., "static/theme.css".
+	Stylesheets []string `yaml:"stylesheets"`
+	Scripts     []string `yaml:"scripts"`
+	// Page is the default page config, which pages can override.
+	Page map[string]interface{} `yaml:"page"`
+}
+
+// Theme customizes the look of a site. It is made of the following files,
+// all of them optional:
+//
+//	layout.html  the page layout, executed with TemplateData
+//	theme.yaml   palettes, linked static files and default page config
+//	static/      files copied into the site, e.g., CSS, JS and fonts
+type Theme struct {
+	fsys   fs.FS
+	layout string
+	cfg    themeConfig
+}
+
+// LoadTheme loads a theme from layers of files. Files of earlier layers
+// override the ones of later layers, e.g., the files of a site override the
+// ones of the theme it uses.
+func LoadTheme(layers ...fs.FS) (*Theme, error) {
+	t := &Theme{
+		fsys: overlayFS(layers),
+	}
+
+	data, err := fs.ReadFile(t.fsys, themeLayoutFile)
+	switch {
+	case err == nil:
+		t.layout = string(data)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	data, err = fs.ReadFile(t.fsys, themeConfigFile)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &t.cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", themeConfigFile, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Apply sets the theme of cfg, and applies the theme palettes to the ones
+// of cfg.
+func (t *Theme) Apply(cfg *Config) error {
+	var err error
+	if cfg.Palette, err = t.cfg.Palette.Apply(cfg.Palette); err != nil {
+		return fmt.Errorf("palette: %w", err)
+	}
+	if cfg.DarkPalette, err = t.cfg.DarkPalette.Apply(cfg.DarkPalette); err != nil {
+		return fmt.Errorf("dark palette: %w", err)
+	}
+	cfg.Theme = t
+
+	return nil
+}
+
+// Static returns the static files of the theme, to be written with the
+// site.
+func (t *Theme) Static() ([]*Asset, error) {
+	var assets []*Asset
+	if err := fs.WalkDir(
+		t.fsys,
+		themeStaticDir,
+		func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			data, err := fs.ReadFile(t.fsys, p)
+			if err != nil {
+				return err
+			}
+			assets = append(assets, &Asset{
+				RelPath: "/" + p,
+				Data:    data,
+			})
+			return nil
+		},
+	); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+func (t *Theme) stylesheets() []string {
+	return absPaths(t.cfg.Stylesheets)
+}
+
+func (t *Theme) scripts() []string {
+	return absPaths(t.cfg.Scripts)
+}
+
+func absPaths(ps []string) []string {
+	var res []string
+	for _, p := range ps {
+		res = append(res, path.Join("/", p))
+	}
+	return res
+}
+
+// overlayFS reads a file from the first layer having it. Directories list
+// the entries of all layers.
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for _, l := range o {
+		f, err := l.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	found := false
+	for _, l := range o {
+		es, err := fs.ReadDir(l, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		for _, e := range es {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
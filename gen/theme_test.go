@@ -0,0 +1,128 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThemeToggle(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	page, err := h.Gen("/a.md.html", []byte("a"))
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Contains(t, html, "--palette-background: #FFFFFF;")
+	require.NotContains(t, html, "prefers-color-scheme")
+	require.NotContains(t, html, "theme_tgl")
+
+	cfg.ThemeToggle = true
+	h, err = NewHtml(cfg)
+	require.NoError(t, err)
+
+	page, err = h.Gen("/a.md.html", []byte("a"))
+	require.NoError(t, err)
+	html = string(page.Html)
+	require.Contains(t, html, "@media (prefers-color-scheme: dark)")
+	require.Contains(t, html, ":root[data-theme=\"dark\"]")
+	require.Equal(t, 3, strings.Count(html, "--palette-background:"))
+	require.Contains(t, html, `<button class="theme_tgl"`)
+	// The choice of the reader is restored in the head.
+	require.Less(
+		t,
+		strings.Index(html, "localStorage.getItem"),
+		strings.Index(html, "</head>"),
+	)
+}
+
+func TestLoadTheme(t *testing.T) {
+	theme := fstest.MapFS{
+		"layout.html": &fstest.MapFile{
+			Data: []byte(`<html><head>{{ range .Stylesheets }}<link href="{{ . }}">{{ end }}</head><body class="theme">{{ .Content.Main.Html }}</body></html>`),
+		},
+		"theme.yaml": &fstest.MapFile{
+			Data: []byte(`
+palette:
+  colors:
+    light_gray: "#EEEEEE"
+  extra:
+    brand: "#5C7CFA"
+stylesheets:
+  - static/theme.css
+page:
+  toc_numbering: true
+  right_pane: toc
+`),
+		},
+		"static/theme.css":       &fstest.MapFile{Data: []byte("body{}")},
+		"static/fonts/serif.ttf": &fstest.MapFile{Data: []byte("font")},
+	}
+	site := fstest.MapFS{
+		"static/theme.css": &fstest.MapFile{Data: []byte("body{color:red}")},
+	}
+
+	th, err := LoadTheme(site, theme)
+	require.NoError(t, err)
+
+	files, err := th.Static()
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Equal(t, "/static/fonts/serif.ttf", files[0].RelPath)
+	require.Equal(t, "/static/theme.css", files[1].RelPath)
+	// The files of the site override the ones of the theme.
+	require.Equal(t, "body{color:red}", string(files[1].Data))
+
+	cfg := DefaultConfig("", ".html")
+	require.NoError(t, th.Apply(&cfg))
+	require.Equal(t, "#EEEEEE", cfg.Palette.LightGray.Hex())
+	require.Equal(t, "#5C7CFA", cfg.Palette.Extra["brand"].Hex())
+
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+	src := []byte(`<!---
+toc_numbering: false
+--->
+# A
+## B
+`)
+	_, err = h.Scan("/a.md.html", src)
+	require.NoError(t, err)
+	page, err := h.Gen("/a.md.html", src)
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Contains(t, html, `<body class="theme"><h1`)
+	require.Contains(t, html, `<link href="/static/theme.css">`)
+
+	// The theme page config applies unless overridden by the page.
+	pCfg, _, err := h.pageConfig(src)
+	require.NoError(t, err)
+	require.Equal(t, "toc", pCfg.rightPane())
+	require.False(t, pCfg.tocConfig(cfg.ToC).Numbering)
+}
+
+func TestThemeHighlightVars(t *testing.T) {
+	cfg := DefaultConfig("", ".html")
+	cfg.ThemeToggle = true
+	p, err := cfg.Palette.Override(nil, map[string]string{"brand": "#5C7CFA"})
+	require.NoError(t, err)
+	cfg.Palette = p
+	h, err := NewHtml(cfg)
+	require.NoError(t, err)
+
+	page, err := h.Gen("/a.md.html", []byte(`<!---
+right_pane: kws
+--->
+<mark b>x</mark> <mark brand>y</mark> <mark name>z</mark>`))
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.Contains(t, html, ".hl_b {\n\tbackground-color: var(--palette-highlighter-green, #AEFF77);\n\tcolor: var(--palette-highlighter-green-contrast, #000000);")
+	require.Contains(t, html, ".hl_brand {\n\tbackground-color: var(--palette-brand, #5C7CFA);\n\tcolor: var(--palette-brand-contrast, #FFFFFF);")
+	require.Contains(t, html, ".kws .namebox.kw_name {\n\tbackground-color: var(--palette-highlighter-red, #FF7792);")
+	// The dark palette overrides the variables.
+	require.Contains(t, html, "--palette-highlighter-green: #3F7A1F;\n")
+	require.Contains(t, html, "--palette-highlighter-green-contrast: #FFFFFF;\n")
+}
@@ -4,11 +4,12 @@ import (
 	"fmt"
 	"html/template"
 
+	"github.com/iamjinlei/proteus/gen/color"
 	"github.com/iamjinlei/proteus/gen/markdown"
 )
 
 const (
-	defaultToCCss = template.CSS(`
+	defaultToCCss = `
 .toc {
 	position: -webkit-sticky; /* Safari */
 	position: sticky;
@@ -18,7 +19,7 @@ const (
 }
 .toc a {
 	text-decoration: none;
-	color: #000000;
+	color: {{ .Palette.Foreground }};
 }
 .toc0_ul {
 	list-style-type: none;
@@ -40,48 +41,198 @@ const (
 	border: none;
 	cursor: pointer;
 }
-`)
+.toc .toc_collapsed {
+	display: none;
+}
+.toc a.toc_active {
+	font-weight: bold;
+}
+.toc_num {
+	color: {{ .Palette.DarkGray }};
+}
+`
+
+	defaultInlineToCCss = `
+.toc.toc_inline {
+	position: static;
+	float: none;
+	margin: 1em 0;
+	padding: 0.5em 0;
+	border-top: 1px solid {{ .Palette.LightGray }};
+	border-bottom: 1px solid {{ .Palette.LightGray }};
+}
+.toc.toc_inline .toc0_ul {
+	padding-left: 0;
+	font-size: 1em;
+}
+`
 
 	defaultToJs = template.JS(`
-function toc_tgl(id) {
-	var btn = document.getElementById(id);
-	var c = document.getElementById(id.replace("tgl", "div"));
-	console.log(btn.innerHTML);
-	console.log(id.replace("tgl", "div"));
-	if (btn.innerHTML === "[+]") {
-	   btn.innerHTML = "[-]";
-	   c.style.display = "inline";
-	} else {
-	   btn.innerHTML = "[+]";
-	   c.style.display = "none";
+document.addEventListener("click", function(e) {
+	var btn = e.target.closest(".toc_tgl");
+	if (!btn) {
+		return;
 	}
-}
+	var c = document.getElementById(btn.id.replace("tgl", "div"));
+	var collapsed = c.classList.toggle("toc_collapsed");
+	btn.textContent = collapsed ? "[+]" : "[-]";
+});
 `)
+
+	defaultToCScrollSpyJs = template.JS(`
+document.addEventListener("DOMContentLoaded", function() {
+	var links = document.querySelectorAll('.toc a[href^="#"]');
+	var targets = [];
+	links.forEach(function(a) {
+		var h = document.getElementById(decodeURIComponent(a.hash.substring(1)));
+		if (h) {
+			targets.push({link: a, heading: h});
+		}
+	});
+	if (targets.length === 0) {
+		return;
+	}
+
+	function spy() {
+		var active = targets[0];
+		targets.forEach(function(t) {
+			if (t.heading.getBoundingClientRect().top < 80) {
+				active = t;
+			}
+		});
+		targets.forEach(function(t) {
+			t.link.classList.toggle("toc_active", t === active);
+		});
+	}
+	window.addEventListener("scroll", spy, {passive: true});
+	spy();
+});
+`)
+)
+
+// ToCConfig controls the table of contents rendering. MinLevel and MaxLevel
+// bound the heading levels listed.
+type ToCConfig struct {
+	MinLevel  int
+	MaxLevel  int
+	Numbering bool
+	Expanded  bool
+	ScrollSpy bool
+}
+
+var (
+	DefaultToCConfig = ToCConfig{
+		MinLevel: 1,
+		MaxLevel: 3,
+	}
 )
 
 func renderToC(
 	hs []*markdown.Heading,
-	maxDepth int,
+	cfg ToCConfig,
+	palette color.Palette,
+) *HtmlComponent {
+	return renderToCWithClass(hs, cfg, palette, "toc", "")
+}
+
+// renderInlineToC renders a table of contents that flows with the main
+// content. Unlike the side panes, it stays visible on narrow screens.
+func renderInlineToC(
+	hs []*markdown.Heading,
+	cfg ToCConfig,
+	palette color.Palette,
+) *HtmlComponent {
+	c := renderToCWithClass(hs, cfg, palette, "toc toc_inline", "inline")
+	if c.Html != "" {
+		c.add(inlineToCChunk(palette))
+	}
+	return c
+}
+
+func inlineToCChunk(palette color.Palette) *markdown.Include {
+	return &markdown.Include{
+		Css: template.CSS(palette.ReplaceIn(defaultInlineToCCss)),
+	}
+}
+
+func renderToCWithClass(
+	hs []*markdown.Heading,
+	cfg ToCConfig,
+	palette color.Palette,
+	class string,
+	idPrefix string,
 ) *HtmlComponent {
+	hs = filterHeadings(hs, cfg.MinLevel, cfg.MaxLevel)
 	if len(hs) == 0 {
 		return &HtmlComponent{}
 	}
 
-	return &HtmlComponent{
+	c := &HtmlComponent{
 		Html: template.HTML(fmt.Sprintf(
-			`<div class="toc">%s</div>`,
-			renderHeadingList(hs, "", 0, maxDepth),
+			`<div class="%s">%s</div>`,
+			class,
+			renderHeadingList(hs, idPrefix, "", 0, cfg),
 		)),
-		Css: defaultToCCss,
-		Js:  defaultToJs,
 	}
+	for _, i := range tocChunks(cfg, palette) {
+		c.add(i)
+	}
+
+	return c
+}
+
+// tocChunks returns the CSS and JS chunks of a table of contents. The
+// scroll spy is only run on pages enabling it.
+func tocChunks(cfg ToCConfig, palette color.Palette) []*markdown.Include {
+	chunks := []*markdown.Include{
+		{
+			Css: template.CSS(palette.ReplaceIn(defaultToCCss)),
+			Js:  defaultToJs,
+		},
+	}
+	if cfg.ScrollSpy {
+		chunks = append(chunks, &markdown.Include{
+			Js:     defaultToCScrollSpyJs,
+			Inline: true,
+		})
+	}
+	return chunks
+}
+
+// filterHeadings returns a copy of the heading tree that only contains
+// headings within [minLevel, maxLevel]. Children of dropped headings above
+// minLevel, as well as of the placeholders for skipped levels, are promoted.
+func filterHeadings(
+	hs []*markdown.Heading,
+	minLevel int,
+	maxLevel int,
+) []*markdown.Heading {
+	var res []*markdown.Heading
+	for _, h := range hs {
+		if h.Level > maxLevel {
+			continue
+		}
+
+		children := filterHeadings(h.Children, minLevel, maxLevel)
+		if h.Level < minLevel || h.Name == "" {
+			res = append(res, children...)
+			continue
+		}
+
+		c := *h
+		c.Children = children
+		res = append(res, &c)
+	}
+
+	return res
 }
 
 func renderHeadingList(
 	hs []*markdown.Heading,
 	idPrefix string,
+	numPrefix string,
 	depth int,
-	maxDepth int,
+	cfg ToCConfig,
 ) string {
 	html := fmt.Sprintf(`<ul class="toc%d_ul">`, depth)
 	for idx, h := range hs {
@@ -89,19 +240,37 @@ func renderHeadingList(
 		if idPrefix != "" {
 			id = idPrefix + "." + id
 		}
+		num := ""
+		if cfg.Numbering {
+			num = fmt.Sprintf("%d", idx+1)
+			if numPrefix != "" {
+				num = numPrefix + "." + num
+			}
+		}
+
+		name := string(h.Html)
+		if num != "" {
+			name = fmt.Sprintf(`<span class="toc_num">%s</span> %s`, num, name)
+		}
 		html += fmt.Sprintf(
 			`<li class="toc%d_li"><a href="#%s">%s</a>`,
 			depth,
 			h.ID,
-			h.Name,
+			name,
 		)
-		if len(h.Children) > 0 && depth+1 < maxDepth {
+		if len(h.Children) > 0 {
+			tgl, class := "[+]", "toc_collapsed"
+			if cfg.Expanded {
+				tgl, class = "[-]", ""
+			}
 			html += fmt.Sprintf(
-				`<button id="toc%s_tgl" class="toc_tgl" onclick="toc_tgl(this.id)" style="display:inline;">[+]</button></li>
-				<div id="toc%s_div" style="display:none;">%s</div>`,
+				`<button id="toc%s_tgl" class="toc_tgl">%s</button></li>
+				<div id="toc%s_div" class="%s">%s</div>`,
 				id,
+				tgl,
 				id,
-				renderHeadingList(h.Children, id, depth+1, maxDepth),
+				class,
+				renderHeadingList(h.Children, id, num, depth+1, cfg),
 			)
 		} else {
 			html += `</li>`
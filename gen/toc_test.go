@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iamjinlei/proteus/gen/color"
+	"github.com/iamjinlei/proteus/gen/markdown"
+)
+
+func TestFilterHeadings(t *testing.T) {
+	hs := []*markdown.Heading{
+		{
+			Level: 1,
+			ID:    "title",
+			Name:  "Title",
+			Html:  "Title",
+			Children: []*markdown.Heading{
+				{
+					Level: 2,
+					ID:    "a",
+					Name:  "A",
+					Html:  "A",
+					Children: []*markdown.Heading{
+						{Level: 3, ID: "a1", Name: "A1", Html: "A1"},
+					},
+				},
+				{Level: 2, ID: "b", Name: "B", Html: "B"},
+			},
+		},
+	}
+
+	res := filterHeadings(hs, 2, 2)
+	require.Equal(t, 2, len(res))
+	require.Equal(t, "A", res[0].Name)
+	require.Nil(t, res[0].Children)
+	require.Equal(t, "B", res[1].Name)
+	// The source tree is left untouched.
+	require.Equal(t, 1, len(hs[0].Children[0].Children))
+
+	c := renderToC(hs, ToCConfig{
+		MinLevel:  2,
+		MaxLevel:  3,
+		Numbering: true,
+		Expanded:  true,
+	}, color.DefaultPalette)
+	require.True(t, strings.Contains(string(c.Html), `<span class="toc_num">1.1</span> A1`))
+	require.True(t, strings.Contains(string(c.Html), `<span class="toc_num">2</span> B`))
+	require.True(t, strings.Contains(string(c.Html), `[-]`))
+	require.False(t, strings.Contains(string(c.Js), "console.log"))
+}
+
+func TestInlineToC(t *testing.T) {
+	h, err := NewHtml(DefaultConfig("", ".html"))
+	require.NoError(t, err)
+
+	for _, marker := range []string{"[[toc]]", `<ins type="toc"/>`} {
+		page, err := h.Gen("/a.md.html", []byte(`Intro
+
+`+marker+`
+
+## A
+
+## B
+`))
+		require.NoError(t, err)
+		html := string(page.Html)
+		require.NotContains(t, html, markdown.ToCPlaceholder)
+		require.NotContains(t, html, "[[toc]]")
+		require.NotContains(t, html, "<p><div")
+		require.Regexp(t, `<p>Intro</p>\s*<div class="toc toc_inline">.*</div>\s*<h2`, html)
+	}
+
+	// The marker is dropped without headings.
+	page, err := h.Gen("/b.md.html", []byte("Intro\n\n[[toc]]\n\nText\n"))
+	require.NoError(t, err)
+	html := string(page.Html)
+	require.NotContains(t, html, markdown.ToCPlaceholder)
+	require.NotContains(t, html, "[[toc]]")
+	require.NotContains(t, html, "toc_inline")
+	require.NotContains(t, html, "<p></p>")
+}
@@ -0,0 +1,306 @@
+package webp
+
+import (
+	"sort"
+)
+
+const (
+	// maxCodeLength is the max length of prefix codes.
+	maxCodeLength = 15
+	// maxLengthCodeLength is the max length of the code coding the code
+	// lengths of prefix codes.
+	maxLengthCodeLength = 7
+
+	numLengthCodes = 19
+	// Code length symbols repeating the previous non zero length, and
+	// repeating zero.
+	repeatPrev  = 16
+	repeatZero  = 17
+	repeatZeroL = 18
+)
+
+// lengthCodeOrder is the order code lengths of the code length code are
+// written in.
+var lengthCodeOrder = [numLengthCodes]int{
+	17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// prefixCode is a canonical Huffman code.
+type prefixCode struct {
+	// lengths are the code lengths of the symbols, 0 for unused ones.
+	lengths []uint8
+	// codes are the bit reversed codes and bits their lengths as written.
+	// A code with a single symbol takes no bits.
+	codes []uint32
+	bits  []uint8
+}
+
+// newPrefixCode returns the code of the symbols counted in hist, with code
+// lengths up to limit.
+func newPrefixCode(hist []uint32, limit int) *prefixCode {
+	c := &prefixCode{
+		lengths: codeLengths(hist, limit),
+		codes:   make([]uint32, len(hist)),
+		bits:    make([]uint8, len(hist)),
+	}
+
+	var count [maxCodeLength + 1]uint32
+	used := 0
+	for _, l := range c.lengths {
+		count[l]++
+		if l > 0 {
+			used++
+		}
+	}
+	if used < 2 {
+		return c
+	}
+
+	// The codes of every length follow the ones of the shorter lengths, in
+	// symbol order.
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		next[l] = code
+		code = (code + count[l]) << 1
+	}
+	for s, l := range c.lengths {
+		if l == 0 {
+			continue
+		}
+		c.codes[s] = reverse(next[l], l)
+		c.bits[s] = l
+		next[l]++
+	}
+
+	return c
+}
+
+// write writes the code of symbol s.
+func (c *prefixCode) write(bw *bitWriter, s int) {
+	bw.write(c.codes[s], uint(c.bits[s]))
+}
+
+// writeLengths writes the code lengths, as a simple code if there are at
+// most two symbols which fit in 8 bits, and as a normal code otherwise.
+func (c *prefixCode) writeLengths(bw *bitWriter) {
+	var syms []int
+	for s, l := range c.lengths {
+		if l > 0 {
+			syms = append(syms, s)
+		}
+	}
+
+	if len(syms) <= 2 && (len(syms) == 0 || syms[len(syms)-1] < 256) {
+		if len(syms) == 0 {
+			syms = []int{0}
+		}
+		bw.write(1, 1)
+		bw.write(uint32(len(syms)-1), 1)
+		if syms[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(syms[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(syms[0]), 8)
+		}
+		if len(syms) == 2 {
+			bw.write(uint32(syms[1]), 8)
+		}
+		return
+	}
+
+	// Normal code, with the code lengths run length encoded and then coded
+	// with the code length code.
+	type rle struct {
+		sym   int
+		extra uint32
+	}
+	var rles []rle
+	prev := uint8(8)
+	for i := 0; i < len(c.lengths); {
+		l := c.lengths[i]
+		run := 1
+		for i+run < len(c.lengths) && c.lengths[i+run] == l {
+			run++
+		}
+		i += run
+
+		if l == 0 {
+			for run >= 3 {
+				if run >= 11 {
+					r := run
+					if r > 138 {
+						r = 138
+					}
+					rles = append(rles, rle{repeatZeroL, uint32(r - 11)})
+					run -= r
+				} else {
+					r := run
+					if r > 10 {
+						r = 10
+					}
+					rles = append(rles, rle{repeatZero, uint32(r - 3)})
+					run -= r
+				}
+			}
+		} else {
+			if l != prev {
+				rles = append(rles, rle{sym: int(l)})
+				prev = l
+				run--
+			}
+			for run >= 3 {
+				r := run
+				if r > 6 {
+					r = 6
+				}
+				rles = append(rles, rle{repeatPrev, uint32(r - 3)})
+				run -= r
+			}
+		}
+		for ; run > 0; run-- {
+			rles = append(rles, rle{sym: int(l)})
+		}
+	}
+
+	hist := make([]uint32, numLengthCodes)
+	for _, r := range rles {
+		hist[r.sym]++
+	}
+	lc := newPrefixCode(hist, maxLengthCodeLength)
+	n := numLengthCodes
+	for n > 4 && lc.lengths[lengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+
+	bw.write(0, 1)
+	bw.write(uint32(n-4), 4)
+	for _, s := range lengthCodeOrder[:n] {
+		bw.write(uint32(lc.lengths[s]), 3)
+	}
+	// All symbols have a code length, instead of up to a max symbol.
+	bw.write(0, 1)
+	for _, r := range rles {
+		lc.write(bw, r.sym)
+		switch r.sym {
+		case repeatPrev:
+			bw.write(r.extra, 2)
+		case repeatZero:
+			bw.write(r.extra, 3)
+		case repeatZeroL:
+			bw.write(r.extra, 7)
+		}
+	}
+}
+
+// codeLengths returns the Huffman code lengths of the symbols counted in
+// hist, up to limit. Counts are raised until the code fits the limit.
+func codeLengths(hist []uint32, limit int) []uint8 {
+	lengths := make([]uint8, len(hist))
+	var syms []int
+	for s, n := range hist {
+		if n > 0 {
+			syms = append(syms, s)
+		}
+	}
+	if len(syms) == 1 {
+		lengths[syms[0]] = 1
+	}
+	if len(syms) < 2 {
+		return lengths
+	}
+
+	for minCount := uint32(1); ; minCount *= 2 {
+		count := func(s int) uint32 {
+			if hist[s] < minCount {
+				return minCount
+			}
+			return hist[s]
+		}
+		sort.SliceStable(syms, func(i, j int) bool {
+			return count(syms[i]) < count(syms[j])
+		})
+
+		// Leaves are sorted by count, and nodes are created in increasing
+		// count order, so the two smallest ones are at the front of either
+		// list.
+		nodes := make([]uint32, 0, 2*len(syms)-1)
+		parent := make([]int, 2*len(syms)-1)
+		for _, s := range syms {
+			nodes = append(nodes, count(s))
+		}
+		leaf, inner := 0, len(syms)
+		smallest := func() int {
+			if leaf < len(syms) &&
+				(inner >= len(nodes) || nodes[leaf] <= nodes[inner]) {
+				leaf++
+				return leaf - 1
+			}
+			inner++
+			return inner - 1
+		}
+		for len(nodes) < cap(nodes) {
+			a := smallest()
+			b := smallest()
+			parent[a] = len(nodes)
+			parent[b] = len(nodes)
+			nodes = append(nodes, nodes[a]+nodes[b])
+		}
+
+		depth := make([]int, len(nodes))
+		longest := 0
+		for i := len(nodes) - 2; i >= 0; i-- {
+			depth[i] = depth[parent[i]] + 1
+			if depth[i] > longest {
+				longest = depth[i]
+			}
+		}
+		if longest > limit {
+			continue
+		}
+		for i, s := range syms {
+			lengths[s] = uint8(depth[i])
+		}
+		return lengths
+	}
+}
+
+// reverse returns the n low bits of v in reverse order.
+func reverse(v uint32, n uint8) uint32 {
+	var r uint32
+	for i := uint8(0); i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
+
+// bitWriter packs bits starting from the least significant bit of every
+// byte.
+type bitWriter struct {
+	buf  []byte
+	bits uint64
+	n    uint
+}
+
+// write writes the n low bits of v, n <= 32.
+func (w *bitWriter) write(v uint32, n uint) {
+	w.bits |= uint64(v) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits >>= 8
+		w.n -= 8
+	}
+}
+
+// bytes returns the written bits, padded to a full byte.
+func (w *bitWriter) bytes() []byte {
+	if w.n > 0 {
+		w.buf = append(w.buf, byte(w.bits))
+		w.bits = 0
+		w.n = 0
+	}
+	return w.buf
+}
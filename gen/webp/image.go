@@ -0,0 +1,185 @@
+package webp
+
+import (
+	"encoding/binary"
+)
+
+const (
+	numLiterals  = 256
+	numLengths   = 24
+	numDistances = 40
+
+	// minMatch and maxMatch are the min and max lengths, in pixels, of
+	// backward references.
+	minMatch = 3
+	maxMatch = 4096
+	// maxDistance is the largest distance the distance codes can express.
+	maxDistance = 1<<20 - 120
+	// maxChain is the max number of earlier positions tried for a match,
+	// and goodMatch the length that ends the search.
+	maxChain  = 32
+	goodMatch = 256
+
+	hashBits = 18
+)
+
+// token is a literal pixel, or a backward reference if length > 0.
+type token struct {
+	pos    int
+	length int
+	dist   int
+}
+
+// writeImage writes the pixels of an entropy coded image of the given
+// width. The main image also has the meta prefix codes flag.
+func writeImage(bw *bitWriter, pix []uint8, width int, main bool) {
+	// No color cache.
+	bw.write(0, 1)
+	if main {
+		// No meta prefix codes.
+		bw.write(0, 1)
+	}
+
+	tokens := backwardRefs(pix, width)
+
+	green := make([]uint32, numLiterals+numLengths)
+	red := make([]uint32, numLiterals)
+	blue := make([]uint32, numLiterals)
+	alpha := make([]uint32, numLiterals)
+	dist := make([]uint32, numDistances)
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, _, _ := prefixEncode(t.length)
+			green[numLiterals+code]++
+			code, _, _ = prefixEncode(distanceCode(t.dist, width))
+			dist[code]++
+			continue
+		}
+		p := t.pos * 4
+		red[pix[p+0]]++
+		green[pix[p+1]]++
+		blue[pix[p+2]]++
+		alpha[pix[p+3]]++
+	}
+
+	codes := [5]*prefixCode{}
+	for i, hist := range [][]uint32{green, red, blue, alpha, dist} {
+		codes[i] = newPrefixCode(hist, maxCodeLength)
+		codes[i].writeLengths(bw)
+	}
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			code, n, extra := prefixEncode(t.length)
+			codes[0].write(bw, numLiterals+code)
+			bw.write(extra, n)
+			code, n, extra = prefixEncode(distanceCode(t.dist, width))
+			codes[4].write(bw, code)
+			bw.write(extra, n)
+			continue
+		}
+		p := t.pos * 4
+		codes[0].write(bw, int(pix[p+1]))
+		codes[1].write(bw, int(pix[p+0]))
+		codes[2].write(bw, int(pix[p+2]))
+		codes[3].write(bw, int(pix[p+3]))
+	}
+}
+
+// backwardRefs splits the pixels into literals and backward references to
+// earlier runs of the same pixels, found greedily with hash chains.
+func backwardRefs(pix []uint8, width int) []token {
+	n := len(pix) / 4
+	argb := make([]uint32, n)
+	for i := range argb {
+		argb[i] = binary.LittleEndian.Uint32(pix[i*4:])
+	}
+
+	head := make([]int, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int, n)
+	hash := func(i int) uint32 {
+		return (argb[i]*0x1e35a7bd ^ argb[i+1]*0x9e3779b1) >> (32 - hashBits)
+	}
+	insert := func(i int) {
+		if i+1 < n {
+			h := hash(i)
+			prev[i] = head[h]
+			head[h] = i
+		}
+	}
+
+	var tokens []token
+	for i := 0; i < n; {
+		bestLen, bestDist := 0, 0
+		try := func(j int) {
+			// A candidate is only longer if it matches at bestLen.
+			if j < 0 || i-j > maxDistance || i+bestLen >= n ||
+				argb[j+bestLen] != argb[i+bestLen] {
+				return
+			}
+			l := 0
+			for i+l < n && l < maxMatch && argb[j+l] == argb[i+l] {
+				l++
+			}
+			if l > bestLen {
+				bestLen, bestDist = l, i-j
+			}
+		}
+		// The previous pixel and the one above have the shortest distance
+		// codes.
+		try(i - 1)
+		try(i - width)
+		if i+1 < n {
+			j := head[hash(i)]
+			for k := 0; j >= 0 && k < maxChain && bestLen < goodMatch; k++ {
+				try(j)
+				j = prev[j]
+			}
+		}
+
+		if bestLen < minMatch {
+			tokens = append(tokens, token{pos: i})
+			insert(i)
+			i++
+			continue
+		}
+		tokens = append(tokens, token{pos: i, length: bestLen, dist: bestDist})
+		for end := i + bestLen; i < end; i++ {
+			insert(i)
+		}
+	}
+
+	return tokens
+}
+
+// distanceCode returns the distance code of a backward reference. The
+// codes up to 120 are offsets in the two dimensional neighbourhood of the
+// pixel, of which only the pixel on the left and the one above are used.
+func distanceCode(dist, width int) int {
+	switch dist {
+	case 1:
+		return 2
+	case width:
+		return 1
+	}
+	return dist + 120
+}
+
+// prefixEncode returns the prefix code, and the number and value of the
+// extra bits, of a backward reference length or distance code v >= 1.
+func prefixEncode(v int) (int, uint, uint32) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+	h := 0
+	for v>>(h+1) > 0 {
+		h++
+	}
+	second := (v >> (h - 1)) & 1
+	n := uint(h - 1)
+	return 2*h + second, n, uint32(v) & (1<<n - 1)
+}
@@ -0,0 +1,310 @@
+// Package webp encodes images in the lossless WebP format, see
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+//
+// The encoder applies the subtract green and predictor transforms and
+// compresses the residuals with LZ77 backward references and a single group
+// of prefix codes. It trades some compression for simplicity: the color
+// cache, the cross color and color indexing transforms and meta prefix codes
+// are not used.
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+)
+
+const (
+	// MaxSize is the max width and height of WebP images.
+	MaxSize = 1 << 14
+
+	signature = 0x2f
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+
+	// predictorBits is the log2 of the predictor tile size.
+	predictorBits = 4
+	numPredictors = 14
+)
+
+var (
+	ErrInvalidSize = errors.New("invalid WebP image size")
+)
+
+// Encode writes img to w as a lossless WebP image.
+func Encode(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > MaxSize || height > MaxSize {
+		return ErrInvalidSize
+	}
+
+	pix := nrgbaPix(img)
+	alpha := uint32(0)
+	for i := 3; i < len(pix); i += 4 {
+		if pix[i] != 0xff {
+			alpha = 1
+			break
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(signature, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(alpha, 1)
+	// Version.
+	bw.write(0, 3)
+
+	// The decoder inverts the transforms in reverse order.
+	subtractGreen(pix)
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+
+	res, modes := predict(pix, width, height)
+	bw.write(1, 1)
+	bw.write(transformPredictor, 2)
+	bw.write(predictorBits-2, 3)
+	writeImage(bw, modes, tiles(width), false)
+
+	bw.write(0, 1)
+	writeImage(bw, res, width, true)
+
+	return writeRIFF(w, bw.bytes())
+}
+
+// writeRIFF writes the VP8L bitstream in a WebP container.
+func writeRIFF(w io.Writer, data []byte) error {
+	pad := len(data) & 1
+	hdr := make([]byte, 20)
+	copy(hdr[0:], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(12+len(data)+pad))
+	copy(hdr[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(len(data)))
+
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad > 0 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nrgbaPix returns the non alpha premultiplied pixels of img in RGBA order.
+func nrgbaPix(img image.Image) []uint8 {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	pix := make([]uint8, w*h*4)
+
+	switch s := img.(type) {
+	case *image.NRGBA:
+		for y := 0; y < h; y++ {
+			i := s.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(pix[y*w*4:(y+1)*w*4], s.Pix[i:i+w*4])
+		}
+
+	case *image.YCbCr:
+		// YCbCr images are opaque, so premultiplied and non premultiplied
+		// colors are the same, and draw converts them fast.
+		rgba := &image.RGBA{
+			Pix:    pix,
+			Stride: w * 4,
+			Rect:   image.Rect(0, 0, w, h),
+		}
+		draw.Draw(rgba, rgba.Rect, s, b.Min, draw.Src)
+
+	default:
+		i := 0
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+				pix[i+0] = c.R
+				pix[i+1] = c.G
+				pix[i+2] = c.B
+				pix[i+3] = c.A
+				i += 4
+			}
+		}
+	}
+
+	return pix
+}
+
+// subtractGreen subtracts the green channel from the red and blue channels.
+func subtractGreen(pix []uint8) {
+	for i := 0; i < len(pix); i += 4 {
+		pix[i+0] -= pix[i+1]
+		pix[i+2] -= pix[i+1]
+	}
+}
+
+// tiles returns the number of predictor tiles covering size pixels.
+func tiles(size int) int {
+	return (size + 1<<predictorBits - 1) >> predictorBits
+}
+
+// predict returns the residuals of the pixels, and the predictor modes of
+// the tiles as an image. The mode of every tile is the one with the
+// smallest residuals.
+func predict(pix []uint8, width, height int) ([]uint8, []uint8) {
+	tw, th := tiles(width), tiles(height)
+	modes := make([]uint8, tw*th*4)
+	for i := 0; i < len(modes); i += 4 {
+		modes[i+3] = 0xff
+	}
+
+	for ty := 0; ty < th; ty++ {
+		for tx := 0; tx < tw; tx++ {
+			// The first row and column have fixed predictors.
+			x0, y0 := tx<<predictorBits, ty<<predictorBits
+			x1, y1 := x0+1<<predictorBits, y0+1<<predictorBits
+			if x0 == 0 {
+				x0 = 1
+			}
+			if y0 == 0 {
+				y0 = 1
+			}
+			if x1 > width {
+				x1 = width
+			}
+			if y1 > height {
+				y1 = height
+			}
+
+			best, bestCost := 0, -1
+			for mode := 0; mode < numPredictors; mode++ {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						p := (y*width + x) * 4
+						pred := predictor(mode, pix, p, p-width*4)
+						for c := 0; c < 4; c++ {
+							cost += abs(int(int8(pix[p+c] - pred[c])))
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[(ty*tw+tx)*4+1] = uint8(best)
+		}
+	}
+
+	res := make([]uint8, len(pix))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			mode := int(modes[((y>>predictorBits)*tw+x>>predictorBits)*4+1])
+			switch {
+			case x == 0 && y == 0:
+				mode = 0
+			case y == 0:
+				mode = 1
+			case x == 0:
+				mode = 2
+			}
+			p := (y*width + x) * 4
+			pred := predictor(mode, pix, p, p-width*4)
+			for c := 0; c < 4; c++ {
+				res[p+c] = pix[p+c] - pred[c]
+			}
+		}
+	}
+
+	return res, modes
+}
+
+// predictor returns the prediction of the pixel at p, with the pixel above
+// at top, in RGBA order. The top right pixel of the rightmost column is the
+// leftmost pixel of the current row, as in the decoder.
+func predictor(mode int, pix []uint8, p, top int) [4]uint8 {
+	var pred [4]uint8
+	switch mode {
+	case 0:
+		pred[3] = 0xff
+		return pred
+	case 1:
+		// L and T are also the predictors of the first row and column,
+		// where the other neighbours are missing.
+		copy(pred[:], pix[p-4:p])
+		return pred
+	case 2:
+		copy(pred[:], pix[top:top+4])
+		return pred
+	case 11:
+		// Select.
+		var l, t int
+		for c := 0; c < 4; c++ {
+			l += abs(int(pix[top-4+c]) - int(pix[top+c]))
+			t += abs(int(pix[top-4+c]) - int(pix[p-4+c]))
+		}
+		if l < t {
+			copy(pred[:], pix[p-4:p])
+		} else {
+			copy(pred[:], pix[top:top+4])
+		}
+		return pred
+	}
+
+	for c := 0; c < 4; c++ {
+		L := pix[p-4+c]
+		T := pix[top+c]
+		TR := pix[top+4+c]
+		TL := pix[top-4+c]
+		switch mode {
+		case 3:
+			pred[c] = TR
+		case 4:
+			pred[c] = TL
+		case 5:
+			pred[c] = avg2(avg2(L, TR), T)
+		case 6:
+			pred[c] = avg2(L, TL)
+		case 7:
+			pred[c] = avg2(L, T)
+		case 8:
+			pred[c] = avg2(TL, T)
+		case 9:
+			pred[c] = avg2(T, TR)
+		case 10:
+			pred[c] = avg2(avg2(L, TL), avg2(T, TR))
+		case 12:
+			pred[c] = clamp(int(L) + int(T) - int(TL))
+		case 13:
+			a := int(avg2(L, T))
+			pred[c] = clamp(a + (a-int(TL))/2)
+		}
+	}
+	return pred
+}
+
+func avg2(a, b uint8) uint8 {
+	return uint8((int(a) + int(b)) / 2)
+}
+
+func clamp(v int) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 0xff {
+		return 0xff
+	}
+	return uint8(v)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,111 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/webp"
+)
+
+func TestEncode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	imgs := map[string]image.Image{}
+
+	gradient := image.NewNRGBA(image.Rect(0, 0, 100, 70))
+	for y := 0; y < 70; y++ {
+		for x := 0; x < 100; x++ {
+			gradient.Set(x, y, color.NRGBA{R: uint8(x * 2), G: uint8(y * 3), B: 50, A: 255})
+		}
+	}
+	imgs["gradient"] = gradient
+
+	// Noise with alpha exercises all predictors and long codes, the
+	// sub-image starts off the origin.
+	noise := image.NewNRGBA(image.Rect(3, 5, 40, 38))
+	rnd.Read(noise.Pix)
+	imgs["noise"] = noise
+
+	// Runs of repeated pixels and rows are backward references.
+	stripes := image.NewNRGBA(image.Rect(0, 0, 300, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 300; x++ {
+			c := color.NRGBA{R: 200, G: 30, B: 30, A: 255}
+			if (x/7+y/5)%3 == 0 {
+				c = color.NRGBA{R: 10, G: 200, B: 90, A: 128}
+			}
+			stripes.Set(x, y, c)
+		}
+	}
+	imgs["stripes"] = stripes
+
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 33, 17), image.YCbCrSubsampleRatio420)
+	rnd.Read(ycbcr.Y)
+	rnd.Read(ycbcr.Cb)
+	rnd.Read(ycbcr.Cr)
+	imgs["ycbcr"] = ycbcr
+
+	paletted := image.NewPaletted(image.Rect(0, 0, 20, 20), palette.Plan9)
+	rnd.Read(paletted.Pix)
+	imgs["paletted"] = paletted
+
+	imgs["pixel"] = image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	imgs["row"] = noise.SubImage(image.Rect(3, 5, 40, 6))
+	imgs["column"] = noise.SubImage(image.Rect(3, 5, 4, 38))
+
+	for name, img := range imgs {
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, img), name)
+		require.Equal(t, "RIFF", string(buf.Bytes()[:4]), name)
+		require.Equal(t, "WEBPVP8L", string(buf.Bytes()[8:16]), name)
+		require.Zero(t, buf.Len()%2, name)
+
+		dec, err := webp.Decode(&buf)
+		require.NoError(t, err, name)
+		b := img.Bounds()
+		require.Equal(t, b.Size(), dec.Bounds().Size(), name)
+		for y := 0; y < b.Dy(); y++ {
+			for x := 0; x < b.Dx(); x++ {
+				require.Equal(
+					t,
+					color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)),
+					dec.At(x, y),
+					"%s at %d,%d", name, x, y,
+				)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, stripes))
+	require.Less(t, buf.Len(), 1000)
+
+	err := Encode(&buf, image.NewNRGBA(image.Rect(0, 0, MaxSize+1, 1)))
+	require.ErrorIs(t, err, ErrInvalidSize)
+	err = Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 0, 1)))
+	require.ErrorIs(t, err, ErrInvalidSize)
+}
+
+func TestCodeLengths(t *testing.T) {
+	// Fibonacci counts make the deepest Huffman codes.
+	hist := make([]uint32, 30)
+	hist[0], hist[1] = 1, 1
+	for i := 2; i < len(hist); i++ {
+		hist[i] = hist[i-1] + hist[i-2]
+	}
+
+	lengths := codeLengths(hist, maxCodeLength)
+	kraft := 0
+	for _, l := range lengths {
+		require.NotZero(t, l)
+		require.LessOrEqual(t, l, uint8(maxCodeLength))
+		kraft += 1 << (maxCodeLength - l)
+	}
+	require.Equal(t, 1<<maxCodeLength, kraft)
+
+	require.Equal(t, []uint8{0, 1, 0}, codeLengths([]uint32{0, 5, 0}, 7))
+}
@@ -2,14 +2,15 @@ module github.com/iamjinlei/proteus
 
 go 1.19
 
-require github.com/gomarkdown/markdown v0.0.0-20240730141124-034f12af3bf6
+require (
+	github.com/gomarkdown/markdown v0.0.0-20240730141124-034f12af3bf6
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/image v0.15.0
+	golang.org/x/net v0.24.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
-	github.com/PuerkitoBio/goquery v1.9.2 // indirect
-	github.com/andybalholm/cascadia v1.3.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.9.0 // indirect
-	golang.org/x/net v0.24.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )